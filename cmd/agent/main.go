@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
@@ -13,7 +14,9 @@ import (
 	"time"
 
 	"github.com/zczy-k/FloatingGateway/internal/config"
+	"github.com/zczy-k/FloatingGateway/internal/control"
 	"github.com/zczy-k/FloatingGateway/internal/doctor"
+	"github.com/zczy-k/FloatingGateway/internal/health/checks"
 	"github.com/zczy-k/FloatingGateway/internal/health/policy"
 	"github.com/zczy-k/FloatingGateway/internal/keepalived"
 	"github.com/zczy-k/FloatingGateway/internal/platform/detect"
@@ -67,7 +70,7 @@ Usage:
 
 Commands:
   run       Run the agent daemon (for continuous health monitoring)
-  check     Perform a single health check (for keepalived track_script)
+  check     Report health as an exit code (for keepalived track_script mode)
   render    Output the rendered keepalived configuration
   apply     Write keepalived config and reload the service
   doctor    Run self-diagnosis checks
@@ -78,10 +81,13 @@ Commands:
 
 Options:
   -c, --config   Path to config file (default: /etc/gateway-agent/config.yaml)
+  --local        (check/status) Run checks locally instead of reading the daemon's state
+  --list-types   (check) List the available check types and their parameters
+  --mode         (check) Check this health profile instead of health.mode
 
 Examples:
   gateway-agent run
-  gateway-agent check --mode=internet
+  gateway-agent check --mode=proxy-generate204
   gateway-agent doctor --fix
   gateway-agent status --json`)
 }
@@ -108,6 +114,40 @@ func loadConfig(path string) (*config.Config, error) {
 		}
 	}
 
+	// Fill in self_ip for extra instances that use unicast
+	for i := range cfg.Instances {
+		inst := &cfg.Instances[i]
+		if inst.PeerIP == "" || inst.SelfIP != "" {
+			continue
+		}
+		info, err := netutil.GetInterfaceInfo(inst.Iface)
+		if err != nil {
+			continue
+		}
+		if inst.IsIPv6() {
+			inst.SelfIP = info.IPv6
+		} else {
+			inst.SelfIP = info.IPv4
+		}
+	}
+
+	// Fill in IPv6 self address and prefix if an IPv6 VIP is configured
+	if cfg.LAN.VIP6 != "" && ((cfg.Routers.PeerIP6 != "" && cfg.Routers.SelfIP6 == "") || cfg.LAN.CIDR6 == "") {
+		info, err := netutil.GetInterfaceInfo(cfg.LAN.Iface)
+		if err == nil && info.IPv6 != "" {
+			if cfg.Routers.PeerIP6 != "" && cfg.Routers.SelfIP6 == "" {
+				cfg.Routers.SelfIP6 = info.IPv6
+			}
+			// The first global prefix may not be the VIP's, e.g. a ULA
+			// VIP next to a delegated prefix
+			if cfg.LAN.CIDR6 == "" {
+				if _, ipNet, err := net.ParseCIDR(info.CIDR6); err == nil && ipNet.Contains(net.ParseIP(cfg.LAN.VIP6)) {
+					cfg.LAN.CIDR6 = info.CIDR6
+				}
+			}
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("validate config: %w", err)
 	}
@@ -121,11 +161,6 @@ func runCmd(args []string) {
 	fs.StringVar(configPath, "config", defaultConfigPath, "config file path")
 	fs.Parse(args)
 
-	// Initialize state file with UNKNOWN on startup to clear any test/stale data
-	stateFile := "/tmp/keepalived.GATEWAY.state"
-	os.WriteFile(stateFile, []byte("UNKNOWN"), 0666)
-	os.Chmod(stateFile, 0666)
-
 	cfg, err := loadConfig(*configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -133,6 +168,9 @@ func runCmd(args []string) {
 	}
 
 	fmt.Printf("Starting gateway-agent (version=%s, role=%s, mode=%s)\n", version.Version, cfg.Role, cfg.Health.Mode)
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
 
 	// Create health policy
 	healthPolicy, err := policy.NewPolicy(cfg)
@@ -141,6 +179,33 @@ func runCmd(args []string) {
 		os.Exit(1)
 	}
 
+	// After a respawn or upgrade, continue the previous process's debounce
+	// state instead of deciding health from the first round
+	restored := false
+	if cfg.Health.StateFile != "" {
+		restored, err = healthPolicy.RestoreState(cfg.Health.StateFile, cfg.StateMaxAge())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: restore health state: %v\n", err)
+		}
+		if restored {
+			fmt.Printf("Restored health state %s from %s\n", healthPolicy.GetState(), cfg.Health.StateFile)
+		}
+	}
+
+	// keepalived kept running across a restart, so its VRRP state is still
+	// valid; otherwise initialize the state files with UNKNOWN to clear any
+	// test/stale data
+	if !restored {
+		keepalived.ResetStateFiles(cfg)
+	}
+
+	// Serve live status to "check" and "status"
+	ctrl := control.NewServer(cfg.Control.Socket, cfg.Control.HTTPListen, version.Version, healthPolicy)
+	if err := ctrl.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: control API unavailable: %v\n", err)
+	}
+	defer ctrl.Close()
+
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
@@ -152,15 +217,70 @@ func runCmd(args []string) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Publish the debounced state to keepalived's track file as a priority
+	// penalty whenever it changes, so a degraded gateway steps its priority
+	// down. The file is left untouched until the first round completes.
+	trackPenalty := 0
+	trackForce := true
+	publish := func(status *policy.Status) {
+		if cfg.Keepalived.Track != config.TrackFile {
+			return
+		}
+		penalty := cfg.TrackPenalty(status.Healthy, status.DegradedLevel)
+		if !trackForce && penalty == trackPenalty {
+			return
+		}
+		if err := keepalived.WriteTrackFile(cfg.Keepalived.TrackFile, penalty); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: write track file: %v\n", err)
+			return
+		}
+		trackPenalty = penalty
+		trackForce = false
+	}
+
+	// Save the debounce state after every round for the next process
+	saveFailed := false
+	saveState := func() {
+		if cfg.Health.StateFile == "" {
+			return
+		}
+		if err := healthPolicy.SaveState(cfg.Health.StateFile); err != nil {
+			if !saveFailed {
+				fmt.Fprintf(os.Stderr, "Warning: save health state: %v\n", err)
+			}
+			saveFailed = true
+			return
+		}
+		saveFailed = false
+	}
+
+	// Checks with their own interval run on their own timers, stopped and
+	// restarted with the policy on reload
+	var stopSched context.CancelFunc
+	startSched := func(p *policy.Policy) {
+		if stopSched != nil {
+			stopSched()
+		}
+		var schedCtx context.Context
+		schedCtx, stopSched = context.WithCancel(ctx)
+		p.Start(schedCtx)
+	}
+	startSched(healthPolicy)
+	defer func() { stopSched() }()
+
 	// Initial check
 	status := healthPolicy.Check(ctx)
 	logStatus(status)
+	publish(status)
+	saveState()
 
 	for {
 		select {
 		case <-ticker.C:
 			status := healthPolicy.Check(ctx)
 			logStatus(status)
+			publish(status)
+			saveState()
 
 		case sig := <-sigCh:
 			switch sig {
@@ -176,8 +296,16 @@ func runCmd(args []string) {
 					fmt.Fprintf(os.Stderr, "Policy reload failed: %v\n", err)
 					continue
 				}
+				// Keep the debounce state, a reload must not look like
+				// a fresh start
+				if newPolicy.InheritState(healthPolicy) {
+					fmt.Printf("Kept health state %s across reload\n", newPolicy.GetState())
+				}
+				startSched(newPolicy)
 				cfg = newCfg
 				healthPolicy = newPolicy
+				ctrl.SetPolicy(newPolicy)
+				trackForce = true
 				fmt.Println("Config reloaded successfully")
 
 			case syscall.SIGINT, syscall.SIGTERM:
@@ -192,6 +320,8 @@ func logStatus(status *policy.Status) {
 	stateStr := "HEALTHY"
 	if !status.Healthy {
 		stateStr = "UNHEALTHY"
+	} else if status.State == policy.StateDegraded {
+		stateStr = "DEGRADED"
 	}
 	fmt.Printf("[%s] %s: %s (%d/%d checks passed)\n",
 		time.Now().Format("15:04:05"),
@@ -206,9 +336,16 @@ func checkCmd(args []string) {
 	fs := flag.NewFlagSet("check", flag.ExitOnError)
 	configPath := fs.String("c", defaultConfigPath, "config file path")
 	fs.StringVar(configPath, "config", defaultConfigPath, "config file path")
-	mode := fs.String("mode", "", "health check mode (basic/internet)")
+	mode := fs.String("mode", "", "health profile to check (basic, internet, domestic, international-direct, proxy-generate204 or one from health.profiles)")
+	local := fs.Bool("local", false, "run checks locally instead of asking the daemon")
+	listTypes := fs.Bool("list-types", false, "list the available check types and their parameters")
 	fs.Parse(args)
 
+	if *listTypes {
+		printCheckTypes()
+		return
+	}
+
 	cfg, err := loadConfig(*configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -217,21 +354,30 @@ func checkCmd(args []string) {
 
 	// Override mode if specified
 	if *mode != "" {
+		if _, ok := cfg.Profile(*mode); !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown health profile %q (%s)\n", *mode, strings.Join(cfg.ProfileNames(), ", "))
+			os.Exit(1)
+		}
 		cfg.Health.Mode = config.HealthMode(*mode)
+		// Validate again with the mode in effect
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: health profile %s: %v\n", *mode, err)
+			os.Exit(1)
+		}
 	}
 
-	// Create health policy
-	healthPolicy, err := policy.NewPolicy(cfg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Prefer the daemon's debounced state; fall back to a one-shot check
+	var status *policy.Status
+	if !*local {
+		status = daemonHealth(cfg)
+	}
+	if status == nil {
+		status, err = localHealth(cfg, 30*time.Second)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
-
-	// Run check
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	status := healthPolicy.Check(ctx)
 
 	// For keepalived track_script: exit 0 = healthy, exit 1 = unhealthy
 	if status.Healthy {
@@ -241,6 +387,47 @@ func checkCmd(args []string) {
 	}
 }
 
+// printCheckTypes lists the registered checker types and their parameters.
+func printCheckTypes() {
+	for _, typ := range checks.Types() {
+		factory, _ := checks.Lookup(typ)
+		fmt.Printf("%s\n    %s\n", typ, factory.Doc)
+		for _, p := range factory.Params {
+			required := ""
+			if p.Required {
+				required = "required"
+			}
+			fmt.Printf("    %-21s %-9s %-8s %s\n", p.Name, p.Kind, required, p.Doc)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Common parameters: %s\n", strings.Join(checks.CommonParams, ", "))
+}
+
+// daemonHealth returns the running daemon's health status, or nil if the
+// daemon is unreachable, checks a different mode, or its status is stale.
+func daemonHealth(cfg *config.Config) *policy.Status {
+	snap, err := control.Query(cfg.Control.Socket, 2*time.Second)
+	if err != nil {
+		return nil
+	}
+	if snap.Mode != string(cfg.Health.Mode) || !snap.Fresh(cfg.ControlMaxAge()) {
+		return nil
+	}
+	return snap.Health
+}
+
+// localHealth runs a single round of checks without debounce history.
+func localHealth(cfg *config.Config, timeout time.Duration) (*policy.Status, error) {
+	healthPolicy, err := policy.NewPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return healthPolicy.Check(ctx), nil
+}
+
 func renderCmd(args []string) {
 	fs := flag.NewFlagSet("render", flag.ExitOnError)
 	configPath := fs.String("c", defaultConfigPath, "config file path")
@@ -319,6 +506,7 @@ func statusCmd(args []string) {
 	configPath := fs.String("c", defaultConfigPath, "config file path")
 	fs.StringVar(configPath, "config", defaultConfigPath, "config file path")
 	jsonOutput := fs.Bool("json", false, "output as JSON")
+	local := fs.Bool("local", false, "run checks locally instead of asking the daemon")
 	fs.Parse(args)
 
 	cfg, err := loadConfig(*configPath)
@@ -335,11 +523,13 @@ func statusCmd(args []string) {
 		Interface  string             `json:"interface"`
 		CIDR       string             `json:"cidr"`
 		VIP        string             `json:"vip"`
+		VIP6       string             `json:"vip6,omitempty"`
 		SelfIP     string             `json:"self_ip"`
 		PeerIP     string             `json:"peer_ip"`
 		HealthMode string             `json:"health_mode"`
 		Keepalived *keepalived.Status `json:"keepalived"`
 		Health     *policy.Status     `json:"health,omitempty"`
+		HealthFrom string             `json:"health_source,omitempty"` // daemon or local
 	}{
 		Version:    version.Version,
 		Role:       string(cfg.Role),
@@ -347,18 +537,25 @@ func statusCmd(args []string) {
 		Interface:  cfg.LAN.Iface,
 		CIDR:       cfg.LAN.CIDR,
 		VIP:        cfg.LAN.VIP,
+		VIP6:       cfg.LAN.VIP6,
 		SelfIP:     cfg.Routers.SelfIP,
 		PeerIP:     cfg.Routers.PeerIP,
 		HealthMode: string(cfg.Health.Mode),
-		Keepalived: keepalived.GetStatus(),
+		Keepalived: keepalived.GetStatus(cfg),
 	}
 
-	// Run a quick health check
-	healthPolicy, err := policy.NewPolicy(cfg)
-	if err == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		status.Health = healthPolicy.Check(ctx)
-		cancel()
+	// Use the daemon's live state, or run a quick health check
+	if !*local {
+		if health := daemonHealth(cfg); health != nil {
+			status.Health = health
+			status.HealthFrom = "daemon"
+		}
+	}
+	if status.Health == nil {
+		if health, err := localHealth(cfg, 10*time.Second); err == nil {
+			status.Health = health
+			status.HealthFrom = "local"
+		}
 	}
 
 	if *jsonOutput {
@@ -373,6 +570,9 @@ func statusCmd(args []string) {
 		fmt.Printf("Interface:    %s\n", status.Interface)
 		fmt.Printf("CIDR:         %s\n", status.CIDR)
 		fmt.Printf("VIP:          %s\n", status.VIP)
+		if status.VIP6 != "" {
+			fmt.Printf("VIP6:         %s\n", status.VIP6)
+		}
 		fmt.Printf("Self IP:      %s\n", status.SelfIP)
 		fmt.Printf("Peer IP:      %s\n", status.PeerIP)
 		fmt.Printf("Health Mode:  %s\n", status.HealthMode)
@@ -385,6 +585,18 @@ func statusCmd(args []string) {
 		if status.Keepalived.VRRPState != "" {
 			fmt.Printf("VRRP State:   %s\n", status.Keepalived.VRRPState)
 		}
+		if len(status.Keepalived.Instances) > 1 {
+			for _, inst := range status.Keepalived.Instances {
+				state := inst.State
+				if state == "" {
+					state = "UNKNOWN"
+				}
+				fmt.Printf("  %-10s  %-8s %-10s %s\n", inst.Name, state, inst.Interface, strings.Join(inst.VIPs, ", "))
+			}
+		}
+		for _, group := range status.Keepalived.Groups {
+			fmt.Printf("Sync Group:   %s %s (%s)\n", group.Name, group.State, strings.Join(group.Instances, ", "))
+		}
 		if status.Health != nil {
 			fmt.Println()
 			fmt.Printf("Health Check\n")
@@ -393,21 +605,59 @@ func statusCmd(args []string) {
 			fmt.Printf("State:        %s\n", status.Health.State)
 			fmt.Printf("Passed:       %d/%d\n", status.Health.PassedCount, status.Health.TotalCount)
 			fmt.Printf("Reason:       %s\n", status.Health.Reason)
+			if status.Health.DegradedLevel > 0 {
+				// Only the track file can carry a partial priority penalty
+				penalty := "priority unchanged with track_script"
+				if cfg.Keepalived.Track == config.TrackFile {
+					penalty = fmt.Sprintf("priority -%d", cfg.TrackPenalty(true, status.Health.DegradedLevel))
+				}
+				fmt.Printf("Degraded:     %s (level %d, %s)\n", status.Health.DegradedReason, status.Health.DegradedLevel, penalty)
+			}
+			if status.Health.FlapPenalty > 0 {
+				damp := fmt.Sprintf("penalty %.0f", status.Health.FlapPenalty)
+				if status.Health.Suppressed {
+					damp += fmt.Sprintf(", state held for ~%ds", status.Health.ReuseInSec)
+				}
+				fmt.Printf("Dampening:    %s\n", damp)
+			}
+			if status.Health.RoundDeadlineMs > 0 {
+				timedOut := 0
+				for _, r := range status.Health.CheckResults {
+					if r.ErrorCode == "CHECK_TIMEOUT" {
+						timedOut++
+					}
+				}
+				round := fmt.Sprintf("%dms (deadline %dms)", status.Health.RoundMs, status.Health.RoundDeadlineMs)
+				if timedOut > 0 {
+					round += fmt.Sprintf(", %d checks timed out", timedOut)
+				}
+				fmt.Printf("Round:        %s\n", round)
+			}
+			fmt.Printf("Source:       %s\n", status.HealthFrom)
 		}
 	}
 }
 
 func notifyCmd(args []string) {
+	fs := flag.NewFlagSet("notify", flag.ExitOnError)
+	group := fs.Bool("group", false, "name refers to a vrrp_sync_group")
+	fs.Parse(args)
+	args = fs.Args()
+
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: gateway-agent notify <state>\n")
+		fmt.Fprintf(os.Stderr, "Usage: gateway-agent notify [--group] <state> [instance|group]\n")
 		os.Exit(1)
 	}
 
 	state := strings.ToUpper(args[0])
+	instance := "GATEWAY"
+	if len(args) > 1 && args[1] != "" {
+		instance = args[1]
+	}
 	cfg, _ := loadConfig(defaultConfigPath)
 
 	// Persist state for status reporting
-	stateFile := "/tmp/keepalived.GATEWAY.state"
+	stateFile := keepalived.StateFile(instance)
 	// Ensure file exists and is writable by everyone (so keepalived user can write to it if needed)
 	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
 		os.WriteFile(stateFile, []byte("UNKNOWN"), 0666)
@@ -421,47 +671,67 @@ func notifyCmd(args []string) {
 		_ = os.WriteFile(stateFile, []byte(state), 0666)
 	}
 
-	iface := "eth0"
-	vip := ""
+	// Member instances announce their own VIPs; the group only records state
+	if *group {
+		fmt.Printf("Sync group %s transitioned to %s\n", instance, state)
+		return
+	}
+
+	var inst *config.InstanceConfig
 	if cfg != nil {
-		iface = cfg.LAN.Iface
-		vip = cfg.LAN.VIP
+		inst = cfg.GetInstance(instance)
 	}
 
 	switch state {
 	case "MASTER":
-		fmt.Printf("Transitioning to MASTER state\n")
-		// Send multiple GARPs to ensure ARP cache update
-		if vip != "" {
-			// Send initial GARP immediately
-			if err := netutil.SendGARP(vip, iface); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: GARP failed: %v\n", err)
-			} else {
-				fmt.Printf("Sent GARP for %s on %s\n", vip, iface)
-			}
-
-			// Send follow-up GARPs asynchronously
-			go func() {
-				for i := 0; i < 3; i++ {
-					time.Sleep(1 * time.Second)
-					if err := netutil.SendGARP(vip, iface); err != nil {
-						fmt.Fprintf(os.Stderr, "Warning: Follow-up GARP failed: %v\n", err)
-					}
+		fmt.Printf("Transitioning %s to MASTER state\n", instance)
+		if inst != nil {
+			for _, vip := range inst.VIPs {
+				if ip, _, err := config.ParseVIP(vip); err == nil {
+					announceVIP(ip.String(), inst.Iface)
 				}
-			}()
+			}
 		}
 
 	case "BACKUP":
-		fmt.Printf("Transitioning to BACKUP state\n")
+		fmt.Printf("Transitioning %s to BACKUP state\n", instance)
 
 	case "FAULT":
-		fmt.Printf("Transitioning to FAULT state\n")
+		fmt.Printf("Transitioning %s to FAULT state\n", instance)
 
 	default:
 		fmt.Printf("Unknown state: %s\n", state)
 	}
 }
 
+// announceVIP sends GARPs (IPv4) or unsolicited NAs (IPv6) so neighbors
+// update their caches to point at this router.
+func announceVIP(vip, iface string) {
+	send := netutil.SendGARP
+	kind := "GARP"
+	if netutil.IsIPv6(vip) {
+		send = netutil.SendUnsolicitedNA
+		kind = "unsolicited NA"
+	}
+
+	// Send initial announcement immediately
+	if err := send(vip, iface); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s failed: %v\n", kind, err)
+	} else {
+		fmt.Printf("Sent %s for %s on %s\n", kind, vip, iface)
+	}
+
+	// Send follow-up announcements asynchronously
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(1 * time.Second)
+			if err := send(vip, iface); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Follow-up %s failed: %v\n", kind, err)
+			}
+		}
+	}()
+}
+
 func detectIfaceCmd(args []string) {
 	iface, err := netutil.DetectPrimaryInterface()
 	if err != nil {
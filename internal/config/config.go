@@ -3,11 +3,16 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"net"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,7 +25,8 @@ const (
 	RoleSecondary Role = "secondary"
 )
 
-// HealthMode represents the health check mode.
+// HealthMode represents the health check mode: the name of the check
+// profile to run.
 type HealthMode string
 
 const (
@@ -28,29 +34,109 @@ const (
 	HealthModeInternet HealthMode = "internet"
 )
 
+// Built-in health profiles, selectable by health.mode without defining
+// them under health.profiles.
+const (
+	ProfileDomestic            = "domestic"             // Domestic DNS and ping only
+	ProfileInternationalDirect = "international-direct" // Foreign targets over the router's own routing
+	ProfileProxyGenerate204    = "proxy-generate204"    // generate_204 through a local proxy on port 7890
+)
+
 // Config is the main configuration structure.
 type Config struct {
-	Version   int             `yaml:"version"`
-	Role      Role            `yaml:"role"`
-	LAN       LANConfig       `yaml:"lan"`
-	Routers   RoutersConfig   `yaml:"routers"`
-	Keepalived KeepalivedConfig `yaml:"keepalived"`
-	Failover  FailoverConfig  `yaml:"failover"`
-	Health    HealthConfig    `yaml:"health"`
-	OpenWrt   OpenWrtConfig   `yaml:"openwrt"`
+	Version    int               `yaml:"version"`
+	Role       Role              `yaml:"role"`
+	LAN        LANConfig         `yaml:"lan"`
+	Routers    RoutersConfig     `yaml:"routers"`
+	Keepalived KeepalivedConfig  `yaml:"keepalived"`
+	Failover   FailoverConfig    `yaml:"failover"`
+	Health     HealthConfig      `yaml:"health"`
+	OpenWrt    OpenWrtConfig     `yaml:"openwrt"`
+	Control    ControlConfig     `yaml:"control"`
+	Instances  []InstanceConfig  `yaml:"instances"` // Extra VRRP instances beyond lan
+	SyncGroups []SyncGroupConfig `yaml:"sync_groups"`
 }
 
 // LANConfig holds LAN interface configuration.
 type LANConfig struct {
 	Iface string `yaml:"iface"`
-	CIDR  string `yaml:"cidr"`  // Optional, inferred from iface if empty
+	CIDR  string `yaml:"cidr"` // Optional, inferred from iface if empty
 	VIP   string `yaml:"vip"`
+	VIP6  string `yaml:"vip6"`  // Optional IPv6 VIP, floated by a separate VRRPv3 instance
+	CIDR6 string `yaml:"cidr6"` // Optional, inferred from iface if empty
 }
 
 // RoutersConfig holds router peer information.
 type RoutersConfig struct {
-	SelfIP string `yaml:"self_ip"` // Optional, inferred from iface
-	PeerIP string `yaml:"peer_ip"` // Required
+	SelfIP  string `yaml:"self_ip"`  // Optional, inferred from iface
+	PeerIP  string `yaml:"peer_ip"`  // Required
+	SelfIP6 string `yaml:"self_ip6"` // Optional, inferred from iface when peer_ip6 is set
+	PeerIP6 string `yaml:"peer_ip6"` // Optional, IPv6 VRRP uses multicast if empty
+}
+
+// InstanceConfig describes an additional VRRP instance, e.g. a guest or IoT
+// VLAN whose gateway should float together with the main LAN.
+type InstanceConfig struct {
+	Name     string                   `yaml:"name"`     // vrrp_instance name, e.g. GUEST
+	Iface    string                   `yaml:"iface"`    // Interface the VIPs live on
+	VIPs     []string                 `yaml:"vips"`     // Addresses of one family, optional /prefix
+	VRID     int                      `yaml:"vrid"`     // Must be unique per interface and family
+	Priority KeepalivedPriorityConfig `yaml:"priority"` // Optional, defaults to keepalived.priority
+	SelfIP   string                   `yaml:"self_ip"`  // Optional, inferred from iface when peer_ip is set
+	PeerIP   string                   `yaml:"peer_ip"`  // Optional, multicast VRRP if empty
+}
+
+// SyncGroupConfig groups instances that must always change state together,
+// e.g. the LAN VIP and an upstream-facing address on the modem segment.
+type SyncGroupConfig struct {
+	Name      string   `yaml:"name"`
+	Instances []string `yaml:"instances"` // Instance names, GATEWAY/GATEWAY6 for lan
+}
+
+// IsIPv6 reports whether the instance floats IPv6 addresses.
+func (i InstanceConfig) IsIPv6() bool {
+	if len(i.VIPs) == 0 {
+		return false
+	}
+	ip, _, err := ParseVIP(i.VIPs[0])
+	return err == nil && ip.To4() == nil
+}
+
+// VIPAddrs returns the instance VIPs with explicit prefixes, as rendered
+// into virtual_ipaddress. Addresses without a prefix get a host prefix.
+func (i InstanceConfig) VIPAddrs() []string {
+	addrs := make([]string, 0, len(i.VIPs))
+	for _, v := range i.VIPs {
+		ip, bits, err := ParseVIP(v)
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, fmt.Sprintf("%s/%d", ip, bits))
+	}
+	return addrs
+}
+
+// ParseVIP parses "addr" or "addr/prefix". Without a prefix the host
+// prefix (32 or 128) is returned.
+func ParseVIP(s string) (net.IP, int, error) {
+	addr, prefix, hasPrefix := strings.Cut(s, "/")
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("%q is not a valid IP address", s)
+	}
+	maxBits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		maxBits = 32
+	}
+	if !hasPrefix {
+		return ip, maxBits, nil
+	}
+	bits, err := strconv.Atoi(prefix)
+	if err != nil || bits < 1 || bits > maxBits {
+		return nil, 0, fmt.Errorf("%q has an invalid prefix length", s)
+	}
+	return ip, bits, nil
 }
 
 // KeepalivedConfig holds VRRP configuration.
@@ -58,8 +144,20 @@ type KeepalivedConfig struct {
 	VRID      int                      `yaml:"vrid"`
 	AdvertInt int                      `yaml:"advert_int"`
 	Priority  KeepalivedPriorityConfig `yaml:"priority"`
+	Track     TrackMode                `yaml:"track"`      // file (default) or script
+	TrackFile string                   `yaml:"track_file"` // Written by the daemon in file mode
 }
 
+// TrackMode selects how keepalived learns about health.
+type TrackMode string
+
+const (
+	// TrackFile has the daemon write its debounced state to a vrrp_track_file.
+	TrackFile TrackMode = "file"
+	// TrackScript has keepalived exec "gateway-agent check" every interval.
+	TrackScript TrackMode = "script"
+)
+
 // KeepalivedPriorityConfig holds priority values for each role.
 type KeepalivedPriorityConfig struct {
 	Primary   int `yaml:"primary"`
@@ -68,21 +166,143 @@ type KeepalivedPriorityConfig struct {
 
 // FailoverConfig holds failover behavior settings.
 type FailoverConfig struct {
-	Prefer         string `yaml:"prefer"`
-	Preempt        bool   `yaml:"preempt"`
-	PreemptDelaySec int   `yaml:"preempt_delay_sec"`
+	Prefer          string `yaml:"prefer"` // primary, secondary or none
+	Preempt         bool   `yaml:"preempt"`
+	PreemptDelaySec int    `yaml:"preempt_delay_sec"`
+	TrackWeight     int    `yaml:"track_weight"` // Negative priority change when unhealthy, 0 = auto
 }
 
+// Failover preferences accepted by FailoverConfig.Prefer.
+const (
+	PreferPrimary   = "primary"
+	PreferSecondary = "secondary"
+	// PreferNone gives both roles the same priority, so whoever holds the
+	// VIP keeps it until its own health fails (sticky mastership).
+	PreferNone = "none"
+)
+
 // HealthConfig holds health check configuration.
 type HealthConfig struct {
-	Mode         HealthMode     `yaml:"mode"`
-	IntervalSec  int            `yaml:"interval_sec"`
-	FailCount    int            `yaml:"fail_count"`
-	RecoverCount int            `yaml:"recover_count"`
-	HoldDownSec  int            `yaml:"hold_down_sec"`
-	KOfN         string         `yaml:"k_of_n"` // e.g., "2/3"
-	Basic        ChecksConfig   `yaml:"basic"`
-	Internet     ChecksConfig   `yaml:"internet"`
+	Mode           HealthMode   `yaml:"mode"`
+	IntervalSec    int          `yaml:"interval_sec"`
+	Workers        int          `yaml:"workers"` // Checks run at once, default 4
+	FailCount      int          `yaml:"fail_count"`
+	RecoverCount   int          `yaml:"recover_count"`
+	HoldDownSec    int          `yaml:"hold_down_sec"`
+	KOfN           string       `yaml:"k_of_n"`          // e.g., "2/3": k passes over the last n rounds
+	KOfNScope      string       `yaml:"k_of_n_scope"`    // aggregate (default) or per_check
+	ScoreThreshold float64      `yaml:"score_threshold"` // 0-1, weighted share of checks that must pass; 0 = all
+	Basic          ChecksConfig `yaml:"basic"`
+	Internet       ChecksConfig `yaml:"internet"`
+	// Named check sets selectable by mode, besides basic, internet and
+	// the built-in profiles (which a profile of the same name replaces)
+	Profiles map[string]ChecksConfig `yaml:"profiles"`
+	// Groups replace the basic/internet check lists when set
+	Groups     []CheckGroupConfig `yaml:"groups"`
+	Expression string             `yaml:"expression"` // e.g. "uplink && foreign"; default all groups
+	Degraded   DegradedConfig     `yaml:"degraded"`
+	Dampening  DampeningConfig    `yaml:"dampening"`
+	// Debounce state kept across agent restarts; empty disables it
+	StateFile      string `yaml:"state_file"`
+	StateMaxAgeSec int    `yaml:"state_max_age_sec"` // Older state is discarded, 0 = 5x interval (min 60s)
+}
+
+// DampeningConfig sets BGP-style flap dampening. Every state change adds
+// a penalty that halves each half-life; while the penalty is above the
+// suppress threshold the state is held until it decays below reuse.
+type DampeningConfig struct {
+	Suppress       float64 `yaml:"suppress"`         // Penalty that suppresses state changes, 0 = off
+	Reuse          float64 `yaml:"reuse"`            // Penalty below which changes resume, default 750
+	Penalty        float64 `yaml:"penalty"`          // Added per state change, default 1000
+	HalfLifeSec    int     `yaml:"half_life_sec"`    // Default 60
+	MaxSuppressSec int     `yaml:"max_suppress_sec"` // Longest suppression, default 4x half_life_sec
+}
+
+// Enabled reports whether flap dampening is on.
+func (d DampeningConfig) Enabled() bool {
+	return d.Suppress > 0
+}
+
+// GetReuse returns the reuse threshold, defaulting to 750.
+func (d DampeningConfig) GetReuse() float64 {
+	if d.Reuse == 0 {
+		return 750
+	}
+	return d.Reuse
+}
+
+// GetPenalty returns the penalty per state change, defaulting to 1000.
+func (d DampeningConfig) GetPenalty() float64 {
+	if d.Penalty == 0 {
+		return 1000
+	}
+	return d.Penalty
+}
+
+// HalfLife returns the penalty half-life, defaulting to 60s.
+func (d DampeningConfig) HalfLife() time.Duration {
+	if d.HalfLifeSec == 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(d.HalfLifeSec) * time.Second
+}
+
+// MaxPenalty returns the penalty ceiling: the value that decays to the
+// reuse threshold within the maximum suppression time.
+func (d DampeningConfig) MaxPenalty() float64 {
+	maxSuppress := time.Duration(d.MaxSuppressSec) * time.Second
+	if maxSuppress == 0 {
+		maxSuppress = 4 * d.HalfLife()
+	}
+	return d.GetReuse() * math.Exp2(float64(maxSuppress)/float64(d.HalfLife()))
+}
+
+// DegradedConfig sets the link quality thresholds of the degraded state.
+// A degraded gateway is still up, but gives up priority in steps so a
+// healthier peer can take over.
+type DegradedConfig struct {
+	LatencyMs    int     `yaml:"latency_ms"`    // Mean latency of passing checks, 0 = off
+	LossPct      float64 `yaml:"loss_pct"`      // Percentage of failed checks, 0 = off
+	Window       int     `yaml:"window"`        // Rounds averaged, default 5
+	PriorityStep int     `yaml:"priority_step"` // Priority lost per degradation level, 0 = auto
+}
+
+// Enabled reports whether any degraded threshold is set.
+func (d DegradedConfig) Enabled() bool {
+	return d.LatencyMs > 0 || d.LossPct > 0
+}
+
+// k-of-n window scopes accepted by HealthConfig.KOfNScope.
+const (
+	// KOfNAggregate windows the round outcome (all checks passed).
+	KOfNAggregate = "aggregate"
+	// KOfNPerCheck windows each check; a round passes if every check is up.
+	KOfNPerCheck = "per_check"
+)
+
+// CheckGroupConfig is a named set of checks aggregated on its own.
+type CheckGroupConfig struct {
+	Name           string        `yaml:"name"`
+	Mode           string        `yaml:"mode"`            // all (default), any, k_of_n, score
+	K              int           `yaml:"k"`               // For k_of_n: checks that must pass
+	ScoreThreshold float64       `yaml:"score_threshold"` // For score: weighted share that must pass
+	Checks         []CheckConfig `yaml:"checks"`
+}
+
+// Check group modes accepted by CheckGroupConfig.Mode.
+const (
+	GroupAll   = "all"
+	GroupAny   = "any"
+	GroupKOfN  = "k_of_n"
+	GroupScore = "score"
+)
+
+// GetMode returns the group's aggregation mode, defaulting to all.
+func (g CheckGroupConfig) GetMode() string {
+	if g.Mode == "" {
+		return GroupAll
+	}
+	return g.Mode
 }
 
 // ChecksConfig holds a list of check configurations.
@@ -92,13 +312,121 @@ type ChecksConfig struct {
 
 // CheckConfig represents a single health check.
 type CheckConfig struct {
-	Type     string `yaml:"type"`     // ping, dns, tcp, http
-	Target   string `yaml:"target"`   // IP, hostname, URL depending on type
-	Port     int    `yaml:"port"`     // For tcp type
-	Resolver string `yaml:"resolver"` // For dns type
-	Domain   string `yaml:"domain"`   // For dns type
-	URL      string `yaml:"url"`      // For http type
-	Timeout  int    `yaml:"timeout"`  // Timeout in seconds, default 5
+	Type     string  `yaml:"type"`     // ping, dns, tcp, http, proxy, tls, script, iface, route, pppoe, resource, process
+	Target   string  `yaml:"target"`   // IP, hostname, URL, interface or process name depending on type
+	Port     int     `yaml:"port"`     // For tcp type, and tls type (default 443)
+	Resolver string  `yaml:"resolver"` // For dns type: host[:port], or a DoH URL
+	Domain   string  `yaml:"domain"`   // For dns type
+	URL      string  `yaml:"url"`      // For http type
+	Timeout  int     `yaml:"timeout"`  // Timeout in seconds, default 5
+	Family   string  `yaml:"family"`   // ipv4, ipv6 or empty for either
+	Weight   float64 `yaml:"weight"`   // Share in the health score, default 1
+	Critical bool    `yaml:"critical"` // Failure marks unhealthy immediately, bypassing fail_count
+
+	// Own schedule instead of every interval_sec round
+	Interval int `yaml:"interval"` // Seconds between runs, 0 = every round
+	Jitter   int `yaml:"jitter"`   // Random extra delay up to this many seconds
+	MaxAge   int `yaml:"max_age"`  // A result older than this counts as failed, 0 = 2x interval + jitter + timeout
+
+	// For http type
+	Method          string            `yaml:"method"` // Default GET
+	Headers         map[string]string `yaml:"headers"`
+	ExpectStatus    []int             `yaml:"expect_status"`     // Default any 2xx/3xx
+	ExpectBody      string            `yaml:"expect_body"`       // Substring the body must contain
+	ExpectBodyRegex string            `yaml:"expect_body_regex"` // Regex the body must match
+	FollowRedirects *bool             `yaml:"follow_redirects"`  // Default true
+	TLSServerName   string            `yaml:"tls_server_name"`   // SNI and verified name (also for tls type)
+	TLSSkipVerify   bool              `yaml:"tls_skip_verify"`
+	TLSCAFile       string            `yaml:"tls_ca_file"` // PEM bundle replacing system roots
+
+	// For tcp type: exchange a payload after connecting, e.g. a Redis
+	// "PING\r\n" expecting "+PONG", or just expect an SSH banner
+	Send   string `yaml:"send"`
+	Expect string `yaml:"expect"` // Substring the response must contain
+
+	// For script type: a root-owned executable run with args; exit code 0
+	// passes. Optional JSON on stdout: {"latency_ms": 12, "message": "..."}
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+
+	// For dns type
+	Transport   string   `yaml:"transport"`    // udp (default), tcp, tls (DoT) or https (DoH)
+	RecordType  string   `yaml:"record_type"`  // A, AAAA, CNAME, MX, NS, TXT; default A/AAAA by family
+	ExpectCIDRs []string `yaml:"expect_cidrs"` // Every address in the answer must fall inside one
+	RejectIPs   []string `yaml:"reject_ips"`   // Known-poisoned addresses or CIDRs that fail the check
+
+	// For http, tcp and proxy types: tunnel through this proxy,
+	// http://[user:pass@]host:port or socks5://[user:pass@]host:port
+	Proxy string `yaml:"proxy"`
+
+	// Pin the check to this router's own egress path
+	SourceIface string `yaml:"source_iface"` // Send through this interface (SO_BINDTODEVICE)
+	SourceIP    string `yaml:"source_ip"`    // Send from this local address
+	Mark        int    `yaml:"mark"`         // Routing mark (SO_MARK) for policy routing
+
+	// Any other key: parameters of types without a field here (route,
+	// resource, process and types registered elsewhere), see --list-types
+	Extra map[string]any `yaml:",inline"`
+
+	// For ping type
+	Count           int     `yaml:"count"`             // Echo requests per check, default 3
+	ProbeIntervalMs int     `yaml:"probe_interval_ms"` // Gap between echo requests, default 200
+	MaxLossPct      float64 `yaml:"max_loss_pct"`      // Fail above this loss; 0 = fail only if all are lost
+}
+
+// DNS transports accepted by CheckConfig.Transport.
+const (
+	DNSTransportUDP   = "udp"
+	DNSTransportTCP   = "tcp"
+	DNSTransportTLS   = "tls"   // DNS over TLS, port 853
+	DNSTransportHTTPS = "https" // DNS over HTTPS, RFC 8484
+)
+
+// ParsePrefix parses a CIDR, or a bare address as a single-host prefix.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return p, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// GetFollowRedirects reports whether an http check follows redirects,
+// defaulting to true.
+func (c CheckConfig) GetFollowRedirects() bool {
+	return c.FollowRedirects == nil || *c.FollowRedirects
+}
+
+// GetMaxAge returns how old the latest result of a check with its own
+// interval may be before it counts as failed.
+func (c CheckConfig) GetMaxAge() time.Duration {
+	if c.MaxAge > 0 {
+		return time.Duration(c.MaxAge) * time.Second
+	}
+	return time.Duration(2*c.Interval+c.Jitter)*time.Second + c.GetTimeout()
+}
+
+// GetTimeout returns the check's timeout, defaulting to 5 seconds.
+func (c CheckConfig) GetTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return 5 * time.Second
+}
+
+// GetWeight returns the check's score weight, defaulting to 1.
+func (c CheckConfig) GetWeight() float64 {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
 }
 
 // OpenWrtConfig holds OpenWrt-specific settings.
@@ -111,6 +439,13 @@ type OpenWrtDHCPConfig struct {
 	AutoSetGateway bool `yaml:"auto_set_gateway"`
 }
 
+// ControlConfig holds the agent daemon's local control API settings.
+type ControlConfig struct {
+	Socket     string `yaml:"socket"`      // Unix socket path served by "run"
+	HTTPListen string `yaml:"http_listen"` // Optional loopback HTTP address, e.g. 127.0.0.1:9099
+	MaxAgeSec  int    `yaml:"max_age_sec"` // Daemon status older than this is ignored, 0 = 3x interval (min 15s)
+}
+
 // DefaultConfig returns a Config with default values filled in.
 func DefaultConfig() *Config {
 	return &Config{
@@ -122,10 +457,12 @@ func DefaultConfig() *Config {
 				Primary:   100,
 				Secondary: 150,
 			},
+			Track:     TrackFile,
+			TrackFile: "/tmp/gateway-agent.track",
 		},
 		Failover: FailoverConfig{
-			Prefer:         "secondary",
-			Preempt:        true,
+			Prefer:          "secondary",
+			Preempt:         true,
 			PreemptDelaySec: 30,
 		},
 		Health: HealthConfig{
@@ -134,6 +471,11 @@ func DefaultConfig() *Config {
 			FailCount:    3,
 			RecoverCount: 5,
 			KOfN:         "2/3",
+			KOfNScope:    KOfNAggregate,
+			Degraded: DegradedConfig{
+				Window: 5,
+			},
+			StateFile: "/var/run/gateway-agent.health",
 			Basic: ChecksConfig{
 				Checks: []CheckConfig{
 					{Type: "ping", Target: "223.5.5.5", Timeout: 3},
@@ -153,6 +495,9 @@ func DefaultConfig() *Config {
 				AutoSetGateway: false,
 			},
 		},
+		Control: ControlConfig{
+			Socket: "/var/run/gateway-agent.sock",
+		},
 	}
 }
 
@@ -174,6 +519,9 @@ func Parse(data []byte) (*Config, error) {
 	return cfg, nil
 }
 
+// validName matches the names of profiles, instances and sync groups.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // Validate checks configuration for errors.
 func (c *Config) Validate() error {
 	// Validate role
@@ -235,14 +583,48 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate IPv6 settings
+	if err := c.validateIPv6(); err != nil {
+		return err
+	}
+
 	// Validate VRID
 	if c.Keepalived.VRID < 1 || c.Keepalived.VRID > 255 {
 		return fmt.Errorf("keepalived.vrid must be between 1 and 255, got %d", c.Keepalived.VRID)
 	}
 
+	// Validate failover priorities
+	if err := c.validateFailover(); err != nil {
+		return err
+	}
+
+	// Validate extra instances
+	if err := c.validateInstances(); err != nil {
+		return err
+	}
+	if err := c.validateSyncGroups(); err != nil {
+		return err
+	}
+
+	// Validate track mode
+	switch c.Keepalived.Track {
+	case TrackFile:
+		if c.Keepalived.TrackFile == "" {
+			return fmt.Errorf("keepalived.track_file is required when keepalived.track is 'file'")
+		}
+	case TrackScript:
+	default:
+		return fmt.Errorf("keepalived.track must be 'file' or 'script', got %q", c.Keepalived.Track)
+	}
+
 	// Validate health mode
-	if c.Health.Mode != HealthModeBasic && c.Health.Mode != HealthModeInternet {
-		return fmt.Errorf("health.mode must be 'basic' or 'internet', got %q", c.Health.Mode)
+	for name := range c.Health.Profiles {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("health.profiles: invalid profile name %q", name)
+		}
+	}
+	if _, ok := c.Profile(string(c.Health.Mode)); !ok {
+		return fmt.Errorf("health.mode %q is not a known profile (%s)", c.Health.Mode, strings.Join(c.ProfileNames(), ", "))
 	}
 
 	// Validate k_of_n if provided
@@ -251,10 +633,440 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("health.k_of_n: %w", err)
 		}
 	}
+	switch c.Health.KOfNScope {
+	case "", KOfNAggregate, KOfNPerCheck:
+	default:
+		return fmt.Errorf("health.k_of_n_scope must be 'aggregate' or 'per_check', got %q", c.Health.KOfNScope)
+	}
+
+	if c.Health.Workers < 0 {
+		return fmt.Errorf("health.workers cannot be negative")
+	}
+	if c.Health.StateMaxAgeSec < 0 {
+		return fmt.Errorf("health.state_max_age_sec cannot be negative")
+	}
+	if c.Health.ScoreThreshold < 0 || c.Health.ScoreThreshold > 1 {
+		return fmt.Errorf("health.score_threshold must be between 0 and 1, got %v", c.Health.ScoreThreshold)
+	}
+	if err := validateChecks("health check", c.GetChecks()); err != nil {
+		return err
+	}
+	// Any profile can be picked later by "check --mode" or the controller
+	for _, name := range c.ProfileNames() {
+		profile, _ := c.Profile(name)
+		if err := validateChecks("health profile "+name+" check", profile.Checks); err != nil {
+			return err
+		}
+	}
+	if err := c.validateGroups(); err != nil {
+		return err
+	}
+	if err := c.validateDegraded(); err != nil {
+		return err
+	}
+	if err := c.validateDampening(); err != nil {
+		return err
+	}
+
+	// Validate control API
+	if c.Control.HTTPListen != "" {
+		host, _, err := net.SplitHostPort(c.Control.HTTPListen)
+		if err != nil {
+			return fmt.Errorf("control.http_listen %q is not valid: %w", c.Control.HTTPListen, err)
+		}
+		ip := net.ParseIP(host)
+		if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("control.http_listen %q must be a loopback address", c.Control.HTTPListen)
+		}
+	}
+	if c.Control.MaxAgeSec < 0 {
+		return fmt.Errorf("control.max_age_sec cannot be negative")
+	}
+
+	return nil
+}
+
+// validateChecks checks the settings of a list of checks; where names
+// the list in errors.
+func validateChecks(where string, checks []CheckConfig) error {
+	for i, check := range checks {
+		if check.Weight < 0 {
+			return fmt.Errorf("%s[%d]: weight cannot be negative", where, i)
+		}
+		if check.Count < 0 || check.ProbeIntervalMs < 0 {
+			return fmt.Errorf("%s[%d]: count and probe_interval_ms cannot be negative", where, i)
+		}
+		if check.MaxLossPct < 0 || check.MaxLossPct > 100 {
+			return fmt.Errorf("%s[%d]: max_loss_pct must be between 0 and 100", where, i)
+		}
+		if check.SourceIP != "" && net.ParseIP(check.SourceIP) == nil {
+			return fmt.Errorf("%s[%d]: invalid source_ip %q", where, i, check.SourceIP)
+		}
+		if check.Mark < 0 {
+			return fmt.Errorf("%s[%d]: mark cannot be negative", where, i)
+		}
+		for _, code := range check.ExpectStatus {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("%s[%d]: invalid expect_status %d", where, i, code)
+			}
+		}
+		if check.Interval < 0 || check.Jitter < 0 || check.MaxAge < 0 {
+			return fmt.Errorf("%s[%d]: interval, jitter and max_age cannot be negative", where, i)
+		}
+		if (check.Jitter > 0 || check.MaxAge > 0) && check.Interval == 0 {
+			return fmt.Errorf("%s[%d]: jitter and max_age require interval", where, i)
+		}
+		if check.Command != "" && !filepath.IsAbs(check.Command) {
+			return fmt.Errorf("%s[%d]: command must be an absolute path, got %q", where, i, check.Command)
+		}
+		switch check.Transport {
+		case "", DNSTransportUDP, DNSTransportTCP, DNSTransportTLS, DNSTransportHTTPS:
+		default:
+			return fmt.Errorf("%s[%d]: transport must be 'udp', 'tcp', 'tls' or 'https', got %q", where, i, check.Transport)
+		}
+		for _, cidr := range append(append([]string{}, check.ExpectCIDRs...), check.RejectIPs...) {
+			if _, err := ParsePrefix(cidr); err != nil {
+				return fmt.Errorf("%s[%d]: %w", where, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateIPv6() error {
+	if c.LAN.VIP6 == "" {
+		if c.Routers.PeerIP6 != "" || c.Routers.SelfIP6 != "" {
+			return fmt.Errorf("routers.peer_ip6/self_ip6 require lan.vip6")
+		}
+		return nil
+	}
+
+	vip6 := net.ParseIP(c.LAN.VIP6)
+	if vip6 == nil || vip6.To4() != nil {
+		return fmt.Errorf("lan.vip6 %q is not a valid IPv6 address", c.LAN.VIP6)
+	}
+
+	if c.LAN.CIDR6 != "" {
+		_, ipNet, err := net.ParseCIDR(c.LAN.CIDR6)
+		if err != nil || ipNet.IP.To4() != nil {
+			return fmt.Errorf("lan.cidr6 %q is not a valid IPv6 CIDR", c.LAN.CIDR6)
+		}
+		// Link-local VIPs are on-link regardless of the global prefix
+		if !vip6.IsLinkLocalUnicast() && !ipNet.Contains(vip6) {
+			return fmt.Errorf("lan.vip6 %q is not within lan.cidr6 %q", c.LAN.VIP6, c.LAN.CIDR6)
+		}
+	}
+
+	for name, value := range map[string]string{
+		"routers.peer_ip6": c.Routers.PeerIP6,
+		"routers.self_ip6": c.Routers.SelfIP6,
+	} {
+		if value == "" {
+			continue
+		}
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("%s %q is not a valid IPv6 address", name, value)
+		}
+		if ip.Equal(vip6) {
+			return fmt.Errorf("%s cannot be the same as lan.vip6", name)
+		}
+	}
+
+	if c.Routers.PeerIP6 != "" && c.Routers.PeerIP6 == c.Routers.SelfIP6 {
+		return fmt.Errorf("routers.self_ip6 cannot be the same as routers.peer_ip6")
+	}
+
+	return nil
+}
+
+func (c *Config) validateInstances() error {
+	type vrrpKey struct {
+		iface string
+		ipv6  bool
+		vrid  int
+	}
+	names := make(map[string]bool)
+	vrids := make(map[vrrpKey]string)
+	for _, inst := range c.GetInstances() {
+		key := vrrpKey{iface: inst.Iface, ipv6: inst.IsIPv6(), vrid: inst.VRID}
+		// GATEWAY and GATEWAY6 come from lan and are validated above
+		if inst.Name == "GATEWAY" || inst.Name == "GATEWAY6" {
+			names[inst.Name] = true
+			vrids[key] = inst.Name
+		}
+	}
+
+	for i, inst := range c.Instances {
+		field := fmt.Sprintf("instances[%d]", i)
+		if !validName.MatchString(inst.Name) {
+			return fmt.Errorf("%s.name %q must contain only letters, digits, '_' or '-'", field, inst.Name)
+		}
+		if names[inst.Name] {
+			return fmt.Errorf("%s.name %q is already used", field, inst.Name)
+		}
+		names[inst.Name] = true
+
+		if inst.Iface == "" {
+			return fmt.Errorf("%s.iface is required", field)
+		}
+		if len(inst.VIPs) == 0 {
+			return fmt.Errorf("%s.vips requires at least one address", field)
+		}
+		ipv6 := inst.IsIPv6()
+		for _, v := range inst.VIPs {
+			ip, _, err := ParseVIP(v)
+			if err != nil {
+				return fmt.Errorf("%s.vips: %w", field, err)
+			}
+			if (ip.To4() == nil) != ipv6 {
+				return fmt.Errorf("%s.vips must all be IPv4 or all IPv6", field)
+			}
+		}
+
+		if inst.VRID < 1 || inst.VRID > 255 {
+			return fmt.Errorf("%s.vrid must be between 1 and 255, got %d", field, inst.VRID)
+		}
+		key := vrrpKey{iface: inst.Iface, ipv6: ipv6, vrid: inst.VRID}
+		if other, ok := vrids[key]; ok {
+			return fmt.Errorf("%s.vrid %d is already used by %s on %s", field, inst.VRID, other, inst.Iface)
+		}
+		vrids[key] = inst.Name
+
+		for name, value := range map[string]string{"peer_ip": inst.PeerIP, "self_ip": inst.SelfIP} {
+			if value == "" {
+				continue
+			}
+			ip := net.ParseIP(value)
+			if ip == nil || (ip.To4() == nil) != ipv6 {
+				return fmt.Errorf("%s.%s %q must be a valid address of the same family as vips", field, name, value)
+			}
+		}
+		if inst.PeerIP != "" && inst.PeerIP == inst.SelfIP {
+			return fmt.Errorf("%s.self_ip cannot be the same as peer_ip", field)
+		}
+	}
 
 	return nil
 }
 
+// validateGroups checks the health check groups and their expression.
+func (c *Config) validateGroups() error {
+	if len(c.Health.Groups) == 0 {
+		if c.Health.Expression != "" {
+			return fmt.Errorf("health.expression requires health.groups")
+		}
+		return nil
+	}
+	if c.Health.ScoreThreshold > 0 {
+		return fmt.Errorf("health.score_threshold cannot be combined with health.groups; use a group with mode 'score'")
+	}
+
+	names := make(map[string]bool)
+	for _, g := range c.Health.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("health.groups: name is required")
+		}
+		if names[g.Name] {
+			return fmt.Errorf("health.groups: duplicate name %q", g.Name)
+		}
+		names[g.Name] = true
+
+		if len(g.Checks) == 0 {
+			return fmt.Errorf("health group %q: at least one check is required", g.Name)
+		}
+		switch g.GetMode() {
+		case GroupAll, GroupAny:
+		case GroupKOfN:
+			if g.K < 1 || g.K > len(g.Checks) {
+				return fmt.Errorf("health group %q: k must be between 1 and %d", g.Name, len(g.Checks))
+			}
+		case GroupScore:
+			if g.ScoreThreshold <= 0 || g.ScoreThreshold > 1 {
+				return fmt.Errorf("health group %q: score_threshold must be between 0 and 1", g.Name)
+			}
+		default:
+			return fmt.Errorf("health group %q: mode must be 'all', 'any', 'k_of_n' or 'score', got %q", g.Name, g.Mode)
+		}
+	}
+
+	expr, err := c.GetExpression()
+	if err != nil {
+		return fmt.Errorf("health.expression: %w", err)
+	}
+	for _, name := range expr.Names() {
+		if !names[name] {
+			return fmt.Errorf("health.expression: unknown group %q", name)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateSyncGroups() error {
+	instances := make(map[string]bool)
+	for _, inst := range c.GetInstances() {
+		instances[inst.Name] = true
+	}
+	// Group names share the notify state file namespace with instances
+	names := make(map[string]bool)
+	for name := range instances {
+		names[name] = true
+	}
+
+	member := make(map[string]string)
+	for i, group := range c.SyncGroups {
+		field := fmt.Sprintf("sync_groups[%d]", i)
+		if !validName.MatchString(group.Name) {
+			return fmt.Errorf("%s.name %q must contain only letters, digits, '_' or '-'", field, group.Name)
+		}
+		if names[group.Name] {
+			return fmt.Errorf("%s.name %q is already used by an instance or group", field, group.Name)
+		}
+		names[group.Name] = true
+
+		if len(group.Instances) < 2 {
+			return fmt.Errorf("%s.instances requires at least two instances", field)
+		}
+		for _, inst := range group.Instances {
+			if !instances[inst] {
+				return fmt.Errorf("%s.instances: unknown instance %q", field, inst)
+			}
+			if other, ok := member[inst]; ok {
+				return fmt.Errorf("%s.instances: %q is already in sync group %s", field, inst, other)
+			}
+			member[inst] = group.Name
+		}
+	}
+
+	return nil
+}
+
+// GetSyncGroup returns the name of the sync group containing the instance,
+// or "" if it is not grouped.
+func (c *Config) GetSyncGroup(instance string) string {
+	for _, group := range c.SyncGroups {
+		for _, name := range group.Instances {
+			if name == instance {
+				return group.Name
+			}
+		}
+	}
+	return ""
+}
+
+// GetInstances returns every VRRP instance the agent manages: GATEWAY (and
+// GATEWAY6 if lan.vip6 is set) built from lan/routers, followed by the
+// extra instances with defaults applied.
+func (c *Config) GetInstances() []InstanceConfig {
+	instances := []InstanceConfig{{
+		Name:     "GATEWAY",
+		Iface:    c.LAN.Iface,
+		VIPs:     []string{c.LAN.VIP},
+		VRID:     c.Keepalived.VRID,
+		Priority: c.Keepalived.Priority,
+		SelfIP:   c.Routers.SelfIP,
+		PeerIP:   c.Routers.PeerIP,
+	}}
+
+	// IPv6 VIPs cannot share a VRRPv2 instance, so they get their own
+	// VRRPv3 instance with the same VRID (v2 and v3 IDs do not collide).
+	// The VIP takes the on-link prefix of lan.cidr6, so hosts on the LAN
+	// see it as on-link like the router's own address.
+	if c.LAN.VIP6 != "" {
+		vip6 := c.LAN.VIP6
+		if _, ipNet, err := net.ParseCIDR(c.LAN.CIDR6); err == nil {
+			bits, _ := ipNet.Mask.Size()
+			vip6 = fmt.Sprintf("%s/%d", vip6, bits)
+		}
+		instances = append(instances, InstanceConfig{
+			Name:     "GATEWAY6",
+			Iface:    c.LAN.Iface,
+			VIPs:     []string{vip6},
+			VRID:     c.Keepalived.VRID,
+			Priority: c.Keepalived.Priority,
+			SelfIP:   c.Routers.SelfIP6,
+			PeerIP:   c.Routers.PeerIP6,
+		})
+	}
+
+	for _, inst := range c.Instances {
+		if inst.Priority.Primary == 0 {
+			inst.Priority.Primary = c.Keepalived.Priority.Primary
+		}
+		if inst.Priority.Secondary == 0 {
+			inst.Priority.Secondary = c.Keepalived.Priority.Secondary
+		}
+		instances = append(instances, inst)
+	}
+
+	return instances
+}
+
+// GetInstance returns the named instance, or nil if it does not exist.
+func (c *Config) GetInstance(name string) *InstanceConfig {
+	for _, inst := range c.GetInstances() {
+		if inst.Name == name {
+			return &inst
+		}
+	}
+	return nil
+}
+
+// CheckWorkers returns how many checks of a round run at once.
+func (c *Config) CheckWorkers() int {
+	if c.Health.Workers > 0 {
+		return c.Health.Workers
+	}
+	return 4
+}
+
+// RoundDeadline returns how long one round of checks may take: nine
+// tenths of the interval, so a slow round finishes before the next tick,
+// but at least a second more than the longest check timeout, so a slow
+// but healthy check isn't cut off.
+func (c *Config) RoundDeadline() time.Duration {
+	interval := c.Health.IntervalSec
+	if interval < 1 {
+		interval = 1
+	}
+	deadline := time.Duration(interval) * time.Second * 9 / 10
+	for _, check := range c.GetChecks() {
+		deadline = max(deadline, check.GetTimeout()+time.Second)
+	}
+	return deadline
+}
+
+// ControlMaxAge returns how old a daemon status may be before clients
+// fall back to running checks themselves.
+func (c *Config) ControlMaxAge() time.Duration {
+	if c.Control.MaxAgeSec > 0 {
+		return time.Duration(c.Control.MaxAgeSec) * time.Second
+	}
+	interval := c.Health.IntervalSec
+	if interval < 1 {
+		interval = 1
+	}
+	maxAge := time.Duration(3*interval) * time.Second
+	if maxAge < 15*time.Second {
+		maxAge = 15 * time.Second
+	}
+	return maxAge
+}
+
+// StateMaxAge returns how old a saved policy state may be to be restored.
+// A respawn or upgrade takes seconds; after longer, the state says
+// little about the link.
+func (c *Config) StateMaxAge() time.Duration {
+	if c.Health.StateMaxAgeSec > 0 {
+		return time.Duration(c.Health.StateMaxAgeSec) * time.Second
+	}
+	maxAge := time.Duration(5*c.Health.IntervalSec) * time.Second
+	if maxAge < 60*time.Second {
+		maxAge = 60 * time.Second
+	}
+	return maxAge
+}
+
 // validateKOfN checks the k/n format.
 func validateKOfN(s string) error {
 	re := regexp.MustCompile(`^(\d+)/(\d+)$`)
@@ -293,28 +1105,281 @@ func ParseKOfN(s string) (k, n int, err error) {
 	return k, n, nil
 }
 
-// GetChecks returns the checks for the configured health mode.
+// GetChecks returns the checks for the configured health mode. With check
+// groups configured it returns the checks of all groups, in group order.
 func (c *Config) GetChecks() []CheckConfig {
-	switch c.Health.Mode {
+	if len(c.Health.Groups) > 0 {
+		var all []CheckConfig
+		for _, g := range c.Health.Groups {
+			all = append(all, g.Checks...)
+		}
+		return all
+	}
+
+	if profile, ok := c.Profile(string(c.Health.Mode)); ok {
+		return profile.Checks
+	}
+	return c.Health.Internet.Checks
+}
+
+// Profile returns the checks of a named profile: health.profiles first,
+// then the basic and internet lists, then the built-in profiles.
+func (c *Config) Profile(name string) (ChecksConfig, bool) {
+	if profile, ok := c.Health.Profiles[name]; ok {
+		return profile, true
+	}
+	switch HealthMode(name) {
 	case HealthModeBasic:
-		return c.Health.Basic.Checks
+		return c.Health.Basic, true
 	case HealthModeInternet:
-		return c.Health.Internet.Checks
-	default:
-		return c.Health.Internet.Checks
+		return c.Health.Internet, true
+	}
+	profile, ok := BuiltinProfiles()[name]
+	return profile, ok
+}
+
+// ProfileNames returns the names health.mode accepts, sorted.
+func (c *Config) ProfileNames() []string {
+	seen := map[string]bool{string(HealthModeBasic): true, string(HealthModeInternet): true}
+	for name := range BuiltinProfiles() {
+		seen[name] = true
+	}
+	for name := range c.Health.Profiles {
+		seen[name] = true
 	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinProfiles returns the check profiles shipped with the agent.
+func BuiltinProfiles() map[string]ChecksConfig {
+	return map[string]ChecksConfig{
+		ProfileDomestic: {
+			Checks: []CheckConfig{
+				{Type: "ping", Target: "223.5.5.5", Timeout: 3},
+				{Type: "dns", Resolver: "119.29.29.29", Domain: "baidu.com", Timeout: 3},
+				{Type: "tcp", Target: "114.114.114.114", Port: 53, Timeout: 3},
+			},
+		},
+		ProfileInternationalDirect: {
+			Checks: []CheckConfig{
+				{Type: "ping", Target: "1.1.1.1", Timeout: 3},
+				{Type: "tcp", Target: "8.8.8.8", Port: 443, Timeout: 3},
+				{Type: "http", URL: "https://www.google.com/generate_204", ExpectStatus: []int{204}, Timeout: 5},
+			},
+		},
+		ProfileProxyGenerate204: {
+			Checks: []CheckConfig{
+				{Type: "ping", Target: "223.5.5.5", Timeout: 3},
+				{Type: "proxy", Proxy: "http://127.0.0.1:7890", URL: "https://www.gstatic.com/generate_204", ExpectStatus: []int{204}, Timeout: 5},
+			},
+		},
+	}
+}
+
+// GetExpression returns the parsed group expression. Without an explicit
+// expression every group must pass. It returns nil if no groups are set.
+func (c *Config) GetExpression() (*Expr, error) {
+	if len(c.Health.Groups) == 0 {
+		return nil, nil
+	}
+	if c.Health.Expression == "" {
+		names := make([]string, len(c.Health.Groups))
+		for i, g := range c.Health.Groups {
+			names[i] = g.Name
+		}
+		return AllOf(names...), nil
+	}
+	return ParseExpr(c.Health.Expression)
 }
 
 // GetPriority returns the priority for the current role.
 func (c *Config) GetPriority() int {
-	switch c.Role {
-	case RolePrimary:
-		return c.Keepalived.Priority.Primary
-	case RoleSecondary:
-		return c.Keepalived.Priority.Secondary
+	return c.priorityFor(c.Role, c.Keepalived.Priority)
+}
+
+// GetInstancePriority returns the instance priority for the current role.
+func (c *Config) GetInstancePriority(inst InstanceConfig) int {
+	return c.priorityFor(c.Role, inst.Priority)
+}
+
+// priorityFor computes a role's priority under failover.prefer. The
+// configured primary/secondary values only provide the high and low
+// priorities; the preferred role always gets the higher one, and with
+// prefer "none" both roles get the same priority.
+func (c *Config) priorityFor(role Role, p KeepalivedPriorityConfig) int {
+	high, low := p.Primary, p.Secondary
+	if low > high {
+		high, low = low, high
+	}
+
+	switch c.Failover.Prefer {
+	case PreferNone:
+		return high
+	case PreferPrimary:
+		if role == RolePrimary {
+			return high
+		}
+		return low
 	default:
-		return 100
+		if role == RoleSecondary {
+			return high
+		}
+		return low
+	}
+}
+
+// TrackWeight returns the keepalived weight applied while unhealthy. Both
+// roles use the same weight, so their relative order is preserved when both
+// are unhealthy: the preferred role keeps the VIP, or with prefer "none" the
+// current master does. The automatic weight is half the high priority,
+// i.e. halfway between the priority gap and the low priority, which flips
+// mastership without flooring either node at 1.
+func (c *Config) TrackWeight() int {
+	if c.Failover.TrackWeight != 0 {
+		return c.Failover.TrackWeight
+	}
+	high := c.Keepalived.Priority.Primary
+	if c.Keepalived.Priority.Secondary > high {
+		high = c.Keepalived.Priority.Secondary
 	}
+	return -(high / 2)
+}
+
+// DegradedStep returns the priority lost per degradation level. The
+// automatic step is a quarter of the unhealthy track weight, so a single
+// threshold crossed stays within the priority gap while several together
+// can hand the VIP to the peer.
+func (c *Config) DegradedStep() int {
+	if c.Health.Degraded.PriorityStep > 0 {
+		return c.Health.Degraded.PriorityStep
+	}
+	step := -c.TrackWeight() / 4
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
+
+// TrackPenalty returns the priority reduction for a health state: the full
+// track weight when unhealthy, otherwise one step per degradation level,
+// capped just below the unhealthy penalty.
+func (c *Config) TrackPenalty(healthy bool, degradedLevel int) int {
+	full := -c.TrackWeight()
+	if !healthy {
+		return full
+	}
+	penalty := degradedLevel * c.DegradedStep()
+	if penalty > full-1 {
+		penalty = full - 1
+	}
+	return penalty
+}
+
+func (c *Config) validateDegraded() error {
+	d := c.Health.Degraded
+	if d.LatencyMs < 0 {
+		return fmt.Errorf("health.degraded.latency_ms cannot be negative")
+	}
+	if d.LossPct < 0 || d.LossPct > 100 {
+		return fmt.Errorf("health.degraded.loss_pct must be between 0 and 100, got %v", d.LossPct)
+	}
+	if d.Window < 0 {
+		return fmt.Errorf("health.degraded.window cannot be negative")
+	}
+	if d.PriorityStep < 0 {
+		return fmt.Errorf("health.degraded.priority_step cannot be negative")
+	}
+	return nil
+}
+
+func (c *Config) validateDampening() error {
+	d := c.Health.Dampening
+	if d.Suppress < 0 || d.Reuse < 0 || d.Penalty < 0 {
+		return fmt.Errorf("health.dampening: suppress, reuse and penalty cannot be negative")
+	}
+	if d.HalfLifeSec < 0 || d.MaxSuppressSec < 0 {
+		return fmt.Errorf("health.dampening: half_life_sec and max_suppress_sec cannot be negative")
+	}
+	if d.Enabled() && d.GetReuse() >= d.Suppress {
+		return fmt.Errorf("health.dampening.reuse (%v) must be below suppress (%v)", d.GetReuse(), d.Suppress)
+	}
+	if d.Enabled() && d.MaxPenalty() <= d.Suppress {
+		return fmt.Errorf("health.dampening: the penalty can never reach suppress (%v) with max_suppress_sec %d", d.Suppress, d.MaxSuppressSec)
+	}
+	return nil
+}
+
+// Warnings returns settings that are valid but probably not what the
+// user wants. "run" logs them at startup and "doctor" reports them.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	// With nopreempt a backup never takes over from a master that is still
+	// advertising, so a health-driven priority drop would be ignored.
+	if !c.Failover.Preempt {
+		warnings = append(warnings, "failover.preempt=false renders nopreempt: an unhealthy master keeps the VIP while it still advertises; use failover.prefer: none for sticky mastership")
+	}
+	return warnings
+}
+
+func (c *Config) validateFailover() error {
+	switch c.Failover.Prefer {
+	case PreferPrimary, PreferSecondary, PreferNone:
+	default:
+		return fmt.Errorf("failover.prefer must be 'primary', 'secondary' or 'none', got %q", c.Failover.Prefer)
+	}
+
+	if c.Failover.TrackWeight > 0 {
+		return fmt.Errorf("failover.track_weight must be negative, got %d", c.Failover.TrackWeight)
+	}
+	weight := -c.TrackWeight()
+
+	check := func(field string, p KeepalivedPriorityConfig) error {
+		for _, v := range []int{p.Primary, p.Secondary} {
+			if v < 1 || v > 254 {
+				return fmt.Errorf("%s values must be between 1 and 254, got %d", field, v)
+			}
+		}
+		high := c.priorityFor(RolePrimary, p)
+		low := c.priorityFor(RoleSecondary, p)
+		if low > high {
+			high, low = low, high
+		}
+		if c.Failover.Prefer != PreferNone && high == low {
+			return fmt.Errorf("%s primary and secondary must differ when failover.prefer is %q", field, c.Failover.Prefer)
+		}
+		// The unhealthy preferred node must fall below the healthy peer
+		if high-weight >= low && high != low {
+			return fmt.Errorf("track weight -%d cannot flip mastership: unhealthy priority %d is not below the peer's %d (%s)", weight, high-weight, low, field)
+		}
+		if high-weight >= high {
+			return fmt.Errorf("track weight -%d cannot flip mastership (%s)", weight, field)
+		}
+		// keepalived floors priorities at 1, which would erase the
+		// tie-break when both nodes are unhealthy
+		if low-weight < 1 {
+			return fmt.Errorf("track weight -%d drops priority %d below 1, losing the tie-break when both nodes are unhealthy (%s)", weight, low, field)
+		}
+		return nil
+	}
+
+	if err := check("keepalived.priority", c.Keepalived.Priority); err != nil {
+		return err
+	}
+	for _, inst := range c.GetInstances() {
+		if inst.Name == "GATEWAY" || inst.Name == "GATEWAY6" {
+			continue
+		}
+		if err := check(fmt.Sprintf("instance %s priority", inst.Name), inst.Priority); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // ToYAML serializes the config to YAML.
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed health group expression such as
+// "uplink && (foreign || domestic)".
+//
+// Operators are "&&" (or "and"), "||" (or "or") and "!" (or "not"), with
+// the usual precedence; parentheses group sub-expressions. Operands are
+// check group names.
+type Expr struct {
+	op   string // "and", "or", "not" or "" for a group name
+	name string
+	args []*Expr
+}
+
+// Eval evaluates the expression given the pass state of each group.
+// Unknown groups evaluate to false.
+func (e *Expr) Eval(groups map[string]bool) bool {
+	switch e.op {
+	case "and":
+		for _, a := range e.args {
+			if !a.Eval(groups) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, a := range e.args {
+			if a.Eval(groups) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !e.args[0].Eval(groups)
+	default:
+		return groups[e.name]
+	}
+}
+
+// Names returns the group names referenced by the expression, in order of
+// first appearance.
+func (e *Expr) Names() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(*Expr)
+	walk = func(x *Expr) {
+		if x.op == "" {
+			if !seen[x.name] {
+				seen[x.name] = true
+				names = append(names, x.name)
+			}
+			return
+		}
+		for _, a := range x.args {
+			walk(a)
+		}
+	}
+	walk(e)
+	return names
+}
+
+// String formats the expression with explicit parentheses.
+func (e *Expr) String() string {
+	switch e.op {
+	case "and", "or":
+		sep := " && "
+		if e.op == "or" {
+			sep = " || "
+		}
+		parts := make([]string, len(e.args))
+		for i, a := range e.args {
+			parts[i] = a.String()
+		}
+		return "(" + strings.Join(parts, sep) + ")"
+	case "not":
+		return "!" + e.args[0].String()
+	default:
+		return e.name
+	}
+}
+
+// AllOf returns an expression requiring every named group to pass.
+func AllOf(names ...string) *Expr {
+	args := make([]*Expr, len(names))
+	for i, name := range names {
+		args[i] = &Expr{name: name}
+	}
+	return &Expr{op: "and", args: args}
+}
+
+// ParseExpr parses a health group expression.
+func ParseExpr(s string) (*Expr, error) {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &exprParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+func tokenizeExpr(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == '!':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case isExprNameChar(c):
+			j := i
+			for j < len(s) && isExprNameChar(rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isExprNameChar(c rune) bool {
+	return c == '_' || c == '-' || c == '.' || (c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)))
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) parseOr() (*Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	args := []*Expr{left}
+	for t := p.peek(); t == "||" || strings.EqualFold(t, "or"); t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, right)
+	}
+	if len(args) == 1 {
+		return left, nil
+	}
+	return &Expr{op: "or", args: args}, nil
+}
+
+func (p *exprParser) parseAnd() (*Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	args := []*Expr{left}
+	for t := p.peek(); t == "&&" || strings.EqualFold(t, "and"); t = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, right)
+	}
+	if len(args) == 1 {
+		return left, nil
+	}
+	return &Expr{op: "and", args: args}, nil
+}
+
+func (p *exprParser) parseUnary() (*Expr, error) {
+	t := p.peek()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "!" || strings.EqualFold(t, "not"):
+		p.pos++
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "not", args: []*Expr{arg}}, nil
+	case t == "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return e, nil
+	case t == ")" || t == "&&" || t == "||" || strings.EqualFold(t, "and") || strings.EqualFold(t, "or"):
+		return nil, fmt.Errorf("unexpected %q", t)
+	default:
+		p.pos++
+		return &Expr{name: t}, nil
+	}
+}
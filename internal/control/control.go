@@ -0,0 +1,182 @@
+// Package control provides the agent daemon's local status API.
+//
+// The daemon ("gateway-agent run") serves its live, debounced health status
+// over a Unix socket (and optionally a loopback HTTP port). Short-lived
+// commands such as "check" and "status" query it instead of running every
+// checker from scratch.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/health/policy"
+)
+
+// Snapshot is the daemon state returned by the status endpoint.
+type Snapshot struct {
+	PID       int            `json:"pid"`
+	Version   string         `json:"version"`
+	Mode      string         `json:"mode"`
+	StartedAt time.Time      `json:"started_at"`
+	Health    *policy.Status `json:"health,omitempty"`
+}
+
+// Fresh reports whether the snapshot holds a health result newer than maxAge.
+func (s *Snapshot) Fresh(maxAge time.Duration) bool {
+	if s == nil || s.Health == nil || s.Health.LastCheck.IsZero() {
+		return false
+	}
+	return time.Since(s.Health.LastCheck) <= maxAge
+}
+
+// Server serves the daemon's current policy status.
+type Server struct {
+	mu        sync.RWMutex
+	policy    *policy.Policy
+	version   string
+	startedAt time.Time
+
+	socketPath string
+	httpListen string
+	servers    []*http.Server
+}
+
+// NewServer creates a control server for the given policy.
+func NewServer(socketPath, httpListen, version string, p *policy.Policy) *Server {
+	return &Server{
+		policy:     p,
+		version:    version,
+		startedAt:  time.Now(),
+		socketPath: socketPath,
+		httpListen: httpListen,
+	}
+}
+
+// SetPolicy swaps the policy served, e.g. after a config reload.
+func (s *Server) SetPolicy(p *policy.Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+}
+
+// Start begins listening on the configured socket and HTTP address.
+func (s *Server) Start() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+
+	if s.socketPath != "" {
+		if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
+			return fmt.Errorf("create socket directory: %w", err)
+		}
+		// Remove a stale socket left behind by a previous instance
+		os.Remove(s.socketPath)
+
+		ln, err := net.Listen("unix", s.socketPath)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", s.socketPath, err)
+		}
+		os.Chmod(s.socketPath, 0660)
+		s.serve(ln, mux)
+	}
+
+	if s.httpListen != "" {
+		ln, err := net.Listen("tcp", s.httpListen)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("listen on %s: %w", s.httpListen, err)
+		}
+		s.serve(ln, mux)
+	}
+
+	return nil
+}
+
+func (s *Server) serve(ln net.Listener, handler http.Handler) {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	s.servers = append(s.servers, srv)
+	go srv.Serve(ln)
+}
+
+// Close stops all listeners and removes the socket file.
+func (s *Server) Close() error {
+	for _, srv := range s.servers {
+		srv.Close()
+	}
+	s.servers = nil
+	if s.socketPath != "" {
+		os.Remove(s.socketPath)
+	}
+	return nil
+}
+
+// Snapshot returns the current daemon state.
+func (s *Server) Snapshot() *Snapshot {
+	s.mu.RLock()
+	p := s.policy
+	s.mu.RUnlock()
+
+	snap := &Snapshot{
+		PID:       os.Getpid(),
+		Version:   s.version,
+		StartedAt: s.startedAt,
+	}
+	if p != nil {
+		snap.Mode = string(p.Mode())
+		snap.Health = p.GetStatus()
+	}
+	return snap
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// Query fetches the daemon state over the Unix socket.
+func Query(socketPath string, timeout time.Duration) (*Snapshot, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("control socket not configured")
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	// Host is ignored, the transport always dials the socket
+	resp, err := client.Get("http://gateway-agent/status")
+	if err != nil {
+		return nil, fmt.Errorf("query daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query daemon: HTTP %d", resp.StatusCode)
+	}
+
+	var snap Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decode daemon status: %w", err)
+	}
+	return &snap, nil
+}
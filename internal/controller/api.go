@@ -152,6 +152,20 @@ func writeError(w http.ResponseWriter, status int, err error) {
 	writeJSON(w, status, map[string]string{"error": err.Error()})
 }
 
+// validHealthMode checks a health mode against the profiles built into
+// the agent. Generated agent configs carry no health.profiles, so custom
+// profiles are rejected here and must be set in the router's own config.
+func validHealthMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	cfg := config.DefaultConfig()
+	if _, ok := cfg.Profile(mode); !ok {
+		return fmt.Errorf("未知的健康检查模式 %q (可选: %s；自定义 profiles 需在路由器的配置文件中设置)", mode, strings.Join(cfg.ProfileNames(), ", "))
+	}
+	return nil
+}
+
 // handleRouters handles /api/routers
 func (s *Server) handleRouters(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -165,6 +179,10 @@ func (s *Server) handleRouters(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
+		if err := validHealthMode(router.HealthMode); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
 		if err := s.manager.AddRouter(&router); err != nil {
 			writeError(w, http.StatusConflict, err)
 			return
@@ -239,6 +257,10 @@ func (s *Server) handleRouterCRUD(w http.ResponseWriter, r *http.Request, router
 			writeError(w, http.StatusBadRequest, err)
 			return
 		}
+		if err := validHealthMode(update.HealthMode); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
 		// Update fields
 		if update.Host != "" {
 			router.Host = update.Host
@@ -436,6 +458,11 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := validHealthMode(update.Health.Mode); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		cfg := s.manager.GetConfig()
 		if update.LAN.VIP != "" && update.LAN.VIP != cfg.LAN.VIP {
 			// Check for conflict
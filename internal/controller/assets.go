@@ -275,8 +275,11 @@ const indexHTML = `<!DOCTYPE html>
                                     <option value="">使用全局设置</option>
                                     <option value="basic">基础模式 (仅检测网关)</option>
                                     <option value="internet">互联网模式 (检测外网)</option>
+                                    <option value="domestic">国内直连 (domestic)</option>
+                                    <option value="international-direct">国际直连 (international-direct)</option>
+                                    <option value="proxy-generate204">代理检测 (proxy-generate204)</option>
                                 </select>
-                                <small class="form-hint">留空则使用全局设置</small>
+                                <small class="form-hint">留空则使用全局设置；仅支持内置模式，自定义 health.profiles 需在路由器的配置文件中手动设置</small>
                             </div>
                         </div>
                         </div>
@@ -320,7 +323,11 @@ const indexHTML = `<!DOCTYPE html>
                                 <select name="health_mode" required>
                                     <option value="internet">互联网模式 (检测外网)</option>
                                     <option value="basic">基础模式 (仅检测网关)</option>
+                                    <option value="domestic">国内直连 (domestic)</option>
+                                    <option value="international-direct">国际直连 (international-direct)</option>
+                                    <option value="proxy-generate204">代理检测 (proxy-generate204)</option>
                                 </select>
+                                <small class="form-hint">仅支持内置模式，自定义 health.profiles 需在路由器的配置文件中手动设置</small>
                             </div>
                         </div>
                     </div>
@@ -727,6 +734,7 @@ section {
 .health-indicator { font-size: 0.8rem; font-weight: 500; }
 .health-indicator.healthy { color: var(--success); }
 .health-indicator.unhealthy { color: var(--danger); }
+.health-indicator.degraded { color: var(--warning); }
 
 .router-actions {
     display: flex;
@@ -2076,10 +2084,16 @@ function renderRouters() {
         
         let healthHtml = '';
         if (router.healthy !== undefined && router.healthy !== null) {
-            const healthClass = router.healthy ? 'healthy' : 'unhealthy';
-            const healthIcon = router.healthy ? '✓' : '✗';
-            const healthText = router.healthy ? '健康' : '异常';
-            healthHtml = '<span class="health-indicator ' + healthClass + '">' + healthIcon + ' ' + healthText + '</span>';
+            let healthClass = router.healthy ? 'healthy' : 'unhealthy';
+            let healthIcon = router.healthy ? '✓' : '✗';
+            let healthText = router.healthy ? '健康' : '异常';
+            if (router.healthy && router.health_state === 'degraded') {
+                healthClass = 'degraded';
+                healthIcon = '!';
+                healthText = '降级';
+            }
+            const healthTitle = router.health_reason ? ' title="' + escapeHtml(router.health_reason) + '"' : '';
+            healthHtml = '<span class="health-indicator ' + healthClass + '"' + healthTitle + '>' + healthIcon + ' ' + healthText + '</span>';
         }
         
         let vrrpHtml = '';
@@ -2149,6 +2163,9 @@ function renderRouters() {
                 '<div><span class="label">Agent:</span> ' + agentVerHtml + '</div>' +
                 '<div><span class="label">VRRP状态:</span> ' + (vrrpHtml || '<span class="value">-</span>') + '</div>' +
                 '<div><span class="label">健康状态:</span> ' + (healthHtml || '<span class="value">-</span>') + '</div>' +
+                (router.health_state === 'degraded' || router.healthy === false
+                    ? '<div><span class="label">原因:</span> <span class="value">' + escapeHtml(router.health_reason || '-') + '</span></div>'
+                    : '') +
             '</div>' +
             progressHtml +
             '<div class="router-actions">' +
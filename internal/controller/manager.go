@@ -55,13 +55,15 @@ type Router struct {
 	Passphrase   string       `yaml:"passphrase,omitempty" json:"passphrase,omitempty"`
 	Role         config.Role  `yaml:"role" json:"role"`
 	Iface        string       `yaml:"iface,omitempty" json:"iface,omitempty"`             // Per-router interface override
-	HealthMode   string       `yaml:"health_mode,omitempty" json:"health_mode,omitempty"` // Per-router health mode (basic/internet)
+	HealthMode   string       `yaml:"health_mode,omitempty" json:"health_mode,omitempty"` // Per-router health profile (basic, internet or a built-in profile)
 	Status       RouterStatus `yaml:"-" json:"status"`
 	Platform     Platform     `yaml:"-" json:"platform"`
 	LastSeen     time.Time    `yaml:"-" json:"last_seen,omitempty"`
 	AgentVer     string       `yaml:"-" json:"agent_version,omitempty"`
 	VRRPState    string       `yaml:"-" json:"vrrp_state,omitempty"`
 	Healthy      *bool        `yaml:"-" json:"healthy,omitempty"`
+	HealthState  string       `yaml:"-" json:"health_state,omitempty"` // healthy, degraded, unhealthy
+	HealthReason string       `yaml:"-" json:"health_reason,omitempty"`
 	Error        string       `yaml:"-" json:"error,omitempty"`
 	InstallLog   []string     `yaml:"-" json:"install_log"`
 	InstallStep  int          `yaml:"-" json:"install_step"`
@@ -333,13 +335,17 @@ func (m *Manager) Probe(r *Router) error {
 					VRRPState string `json:"vrrp_state"`
 				} `json:"keepalived"`
 				Health struct {
-					Healthy bool `json:"healthy"`
+					Healthy bool   `json:"healthy"`
+					State   string `json:"state"`
+					Reason  string `json:"reason"`
 				} `json:"health"`
 			}
 			if json.Unmarshal([]byte(extractJSON(output)), &status) == nil {
 				r.VRRPState = status.Keepalived.VRRPState
 				healthy := status.Health.Healthy
 				r.Healthy = &healthy
+				r.HealthState = status.Health.State
+				r.HealthReason = status.Health.Reason
 			}
 		}
 	}
@@ -1514,6 +1520,8 @@ func (m *Manager) Uninstall(r *Router) error {
 	r.AgentVer = ""
 	r.VRRPState = ""
 	r.Healthy = nil
+	r.HealthState = ""
+	r.HealthReason = ""
 
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -61,11 +62,24 @@ func (d *Doctor) Run() *Report {
 	// Run all checks
 	report.Checks = append(report.Checks, d.checkInterface())
 	report.Checks = append(report.Checks, d.checkCIDR())
+	if len(d.cfg.Instances) > 0 {
+		report.Checks = append(report.Checks, d.checkInstances())
+	}
 	report.Checks = append(report.Checks, d.checkVIP())
 	report.Checks = append(report.Checks, d.checkVIPConflict())
+	if d.cfg.LAN.VIP6 != "" {
+		report.Checks = append(report.Checks, d.checkVIP6())
+	}
 	report.Checks = append(report.Checks, d.checkPeerIP())
+	if len(d.cfg.Warnings()) > 0 {
+		report.Checks = append(report.Checks, d.checkConfigWarnings())
+	}
 	report.Checks = append(report.Checks, d.checkKeepalived())
 	report.Checks = append(report.Checks, d.checkKeepalviedConfig())
+	report.Checks = append(report.Checks, d.checkTrackFile())
+	if d.hasCheckSources() {
+		report.Checks = append(report.Checks, d.checkCheckSources())
+	}
 	report.Checks = append(report.Checks, d.checkVRRPMulticast())
 	report.Checks = append(report.Checks, d.checkArping())
 
@@ -128,6 +142,27 @@ func (d *Doctor) checkInterface() CheckResult {
 	return result
 }
 
+func (d *Doctor) checkInstances() CheckResult {
+	result := CheckResult{Name: "vrrp_instances"}
+
+	var missing []string
+	for _, inst := range d.cfg.Instances {
+		if !netutil.InterfaceExists(inst.Iface) {
+			missing = append(missing, fmt.Sprintf("%s (%s)", inst.Name, inst.Iface))
+		}
+	}
+
+	if len(missing) > 0 {
+		result.Status = "error"
+		result.Message = fmt.Sprintf("Interfaces not found for instances: %s", strings.Join(missing, ", "))
+		return result
+	}
+
+	result.Status = "ok"
+	result.Message = fmt.Sprintf("%d extra VRRP instance(s) configured", len(d.cfg.Instances))
+	return result
+}
+
 func (d *Doctor) checkCIDR() CheckResult {
 	result := CheckResult{Name: "cidr_valid"}
 
@@ -194,6 +229,90 @@ func (d *Doctor) checkVIP() CheckResult {
 	return result
 }
 
+func (d *Doctor) checkConfigWarnings() CheckResult {
+	return CheckResult{
+		Name:    "config_warnings",
+		Status:  "warning",
+		Message: strings.Join(d.cfg.Warnings(), "; "),
+	}
+}
+
+func (d *Doctor) checkVIP6() CheckResult {
+	result := CheckResult{Name: "vip6_valid"}
+
+	if !netutil.IsIPv6(d.cfg.LAN.VIP6) {
+		result.Status = "error"
+		result.Message = fmt.Sprintf("VIP6 %q is not a valid IPv6 address", d.cfg.LAN.VIP6)
+		return result
+	}
+
+	info, err := netutil.GetInterfaceInfo(d.cfg.LAN.Iface)
+	if err == nil && info.IPv6 == "" && !net.ParseIP(d.cfg.LAN.VIP6).IsLinkLocalUnicast() {
+		result.Status = "warning"
+		result.Message = fmt.Sprintf("Interface %q has no global IPv6 address for VIP6 %s", d.cfg.LAN.Iface, d.cfg.LAN.VIP6)
+		return result
+	}
+
+	if d.cfg.Routers.PeerIP6 == "" {
+		result.Status = "ok"
+		result.Message = fmt.Sprintf("VIP6 %s is valid (IPv6 VRRP uses multicast, routers.peer_ip6 not set)", d.cfg.LAN.VIP6)
+		return result
+	}
+
+	result.Status = "ok"
+	result.Message = fmt.Sprintf("VIP6 %s is valid", d.cfg.LAN.VIP6)
+	return result
+}
+
+func (d *Doctor) hasCheckSources() bool {
+	for _, check := range d.cfg.GetChecks() {
+		if check.SourceIface != "" || check.SourceIP != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCheckSources verifies that health check source bindings exist locally.
+func (d *Doctor) checkCheckSources() CheckResult {
+	result := CheckResult{Name: "check_sources"}
+
+	var problems []string
+	for i, check := range d.cfg.GetChecks() {
+		if check.SourceIface != "" {
+			if _, err := net.InterfaceByName(check.SourceIface); err != nil {
+				problems = append(problems, fmt.Sprintf("check[%d] source_iface %q not found", i, check.SourceIface))
+			}
+		}
+		if check.SourceIP != "" && !hasLocalAddr(check.SourceIP) {
+			problems = append(problems, fmt.Sprintf("check[%d] source_ip %s is not assigned locally", i, check.SourceIP))
+		}
+	}
+
+	if len(problems) > 0 {
+		result.Status = "warning"
+		result.Message = strings.Join(problems, "; ")
+		return result
+	}
+	result.Status = "ok"
+	result.Message = "Health check source interfaces and addresses are present"
+	return result
+}
+
+func hasLocalAddr(ip string) bool {
+	want := net.ParseIP(ip)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil || want == nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(want) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Doctor) checkVIPConflict() CheckResult {
 	result := CheckResult{Name: "vip_conflict"}
 
@@ -421,6 +540,52 @@ func (d *Doctor) checkKeepalviedConfig() CheckResult {
 	return result
 }
 
+func (d *Doctor) checkTrackFile() CheckResult {
+	result := CheckResult{Name: "track_file"}
+
+	if d.cfg.Keepalived.Track != config.TrackFile {
+		result.Status = "ok"
+		result.Message = "keepalived 使用 track_script 模式，无需状态文件"
+		return result
+	}
+
+	path := d.cfg.Keepalived.TrackFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Status = "error"
+		result.Message = fmt.Sprintf("健康状态文件不存在: %s (gateway-agent run 是否在运行?)", path)
+		result.CanFix = true
+		if d.autoFix {
+			if err := keepalived.WriteTrackFile(path, d.cfg.TrackPenalty(false, 0)); err == nil {
+				result.Fixed = true
+				result.Status = "warning"
+				result.Message = fmt.Sprintf("已创建健康状态文件 %s (初始为不健康，等待 agent 更新)", path)
+			}
+		}
+		return result
+	}
+
+	value := strings.TrimSpace(string(data))
+	penalty, err := strconv.Atoi(value)
+	full := d.cfg.TrackPenalty(false, 0)
+	if err != nil || penalty < 0 || penalty > full {
+		result.Status = "warning"
+		result.Message = fmt.Sprintf("健康状态文件 %s 内容异常: %q", path, value)
+		return result
+	}
+
+	state := "健康"
+	switch {
+	case penalty == full:
+		state = "不健康"
+	case penalty > 0:
+		state = fmt.Sprintf("降级 (优先级 -%d)", penalty)
+	}
+	result.Status = "ok"
+	result.Message = fmt.Sprintf("健康状态文件 %s: %s", path, state)
+	return result
+}
+
 func (d *Doctor) checkVRRPMulticast() CheckResult {
 	result := CheckResult{Name: "vrrp_multicast"}
 
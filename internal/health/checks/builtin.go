@@ -0,0 +1,243 @@
+package checks
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// TLS client parameters shared by the types that speak TLS.
+var tlsParams = []Param{
+	{Name: "tls_server_name", Kind: KindString, Doc: "SNI and verified name"},
+	{Name: "tls_ca_file", Kind: KindString, Doc: "PEM bundle replacing the system roots"},
+	{Name: "tls_skip_verify", Kind: KindBool, Doc: "Don't verify the certificate"},
+}
+
+// HTTP request parameters, also used by proxy checks with a url.
+var httpParams = append([]Param{
+	{Name: "method", Kind: KindString, Doc: "Request method, default GET"},
+	{Name: "headers", Kind: KindMap, Doc: "Request headers; Host overrides the virtual host"},
+	{Name: "expect_status", Kind: KindInts, Doc: "Accepted status codes, default any 2xx/3xx"},
+	{Name: "expect_body", Kind: KindString, Doc: "Substring the body must contain"},
+	{Name: "expect_body_regex", Kind: KindString, Doc: "Regex the body must match"},
+	{Name: "follow_redirects", Kind: KindBool, Doc: "Follow up to 3 redirects, default true"},
+}, tlsParams...)
+
+func init() {
+	Register("ping", Factory{
+		Doc: "ICMP echo with loss and RTT statistics",
+		Params: []Param{
+			{Name: "target", Kind: KindString, Required: true, Doc: "Host or IP address"},
+			{Name: "count", Kind: KindInt, Doc: "Echo requests per check, default 3"},
+			{Name: "probe_interval_ms", Kind: KindInt, Doc: "Gap between echo requests, default 200"},
+			{Name: "max_loss_pct", Kind: KindFloat, Doc: "Fail above this loss; 0 = only if all are lost"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			count := cfg.Count
+			if count == 0 {
+				count = 3
+			}
+			interval := time.Duration(cfg.ProbeIntervalMs) * time.Millisecond
+			if interval == 0 {
+				interval = 200 * time.Millisecond
+			}
+			return &PingChecker{
+				target:     cfg.Target,
+				timeout:    o.Timeout,
+				family:     o.Family,
+				count:      count,
+				interval:   interval,
+				maxLossPct: cfg.MaxLossPct,
+				source:     o.Source,
+			}, nil
+		},
+	})
+
+	Register("dns", Factory{
+		Doc: "DNS query over UDP, TCP, DoT or DoH with answer validation",
+		Params: append([]Param{
+			{Name: "resolver", Kind: KindString, Required: true, Doc: "host[:port], or a DoH URL"},
+			{Name: "domain", Kind: KindString, Required: true, Doc: "Name to resolve"},
+			{Name: "transport", Kind: KindString, Doc: "udp (default), tcp, tls or https"},
+			{Name: "record_type", Kind: KindString, Doc: "A, AAAA, CNAME, MX, NS, PTR or TXT; default A/AAAA by family"},
+			{Name: "expect_cidrs", Kind: KindStrings, Doc: "Every address in the answer must fall inside one"},
+			{Name: "reject_ips", Kind: KindStrings, Doc: "Known-poisoned addresses or CIDRs"},
+		}, tlsParams...),
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return newDNSChecker(cfg, o.Timeout, o.Family, o.Source)
+		},
+	})
+
+	Register("tcp", Factory{
+		Doc: "TCP connect, optionally exchanging a payload",
+		Params: []Param{
+			{Name: "target", Kind: KindString, Required: true, Doc: "Host or IP address"},
+			{Name: "port", Kind: KindInt, Required: true, Doc: "Port to connect to"},
+			{Name: "send", Kind: KindString, Doc: "Payload written after connecting"},
+			{Name: "expect", Kind: KindString, Doc: "Substring the response must contain"},
+			{Name: "proxy", Kind: KindString, Doc: "Tunnel through http:// or socks5:// proxy"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return newTCPChecker(cfg, o.Timeout, o.Family, o.Source, o.Proxy), nil
+		},
+	})
+
+	Register("http", Factory{
+		Doc: "HTTP request with status and body assertions",
+		Params: append([]Param{
+			{Name: "url", Kind: KindString, Doc: "URL to request (target is accepted too)"},
+			{Name: "target", Kind: KindString, Doc: "Alias of url"},
+			{Name: "proxy", Kind: KindString, Doc: "Tunnel through http:// or socks5:// proxy"},
+		}, httpParams...),
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			url := cfg.URL
+			if url == "" && cfg.Target != "" {
+				url = cfg.Target
+			}
+			if url == "" {
+				return nil, fmt.Errorf("http check requires url or target")
+			}
+			return newHTTPChecker(cfg, url, o.Timeout, o.Family, o.Source, o.Proxy)
+		},
+	})
+
+	Register("proxy", Factory{
+		Doc: "Reachability through a local proxy: HTTP request to url, or TCP tunnel to target:port",
+		Params: append([]Param{
+			{Name: "proxy", Kind: KindString, Required: true, Doc: "http:// or socks5:// proxy, with optional user:pass@"},
+			{Name: "url", Kind: KindString, Doc: "URL to request through the proxy"},
+			{Name: "target", Kind: KindString, Doc: "Host to tunnel to when no url is set"},
+			{Name: "port", Kind: KindInt, Doc: "Port to tunnel to when no url is set"},
+			{Name: "send", Kind: KindString, Doc: "Payload written through the tunnel"},
+			{Name: "expect", Kind: KindString, Doc: "Substring the tunneled response must contain"},
+		}, httpParams...),
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			// Measures the proxy path itself: an HTTP request if a url is
+			// given, otherwise a TCP tunnel to target:port
+			var inner Checker
+			if cfg.URL != "" {
+				hc, err := newHTTPChecker(cfg, cfg.URL, o.Timeout, o.Family, o.Source, o.Proxy)
+				if err != nil {
+					return nil, err
+				}
+				inner = hc
+			} else {
+				if cfg.Target == "" || cfg.Port == 0 {
+					return nil, fmt.Errorf("proxy check requires url, or target and port")
+				}
+				inner = newTCPChecker(cfg, o.Timeout, o.Family, o.Source, o.Proxy)
+			}
+			return &ProxyChecker{inner: inner, proxy: o.Proxy}, nil
+		},
+	})
+
+	Register("tls", Factory{
+		Doc: "TLS handshake reporting the protocol and certificate expiry",
+		Params: append([]Param{
+			{Name: "target", Kind: KindString, Required: true, Doc: "Host or IP address; also the default SNI"},
+			{Name: "port", Kind: KindInt, Doc: "Port, default 443"},
+		}, tlsParams...),
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return newTLSChecker(cfg, o.Timeout, o.Family, o.Source)
+		},
+	})
+
+	Register("iface", Factory{
+		Doc: "Local interface is up, has carrier and an address",
+		Params: []Param{
+			{Name: "target", Kind: KindString, Required: true, Doc: "Interface name, e.g. eth1"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return &IfaceChecker{iface: cfg.Target, family: o.Family}, nil
+		},
+	})
+
+	Register("route", Factory{
+		Doc: "A default route exists in the main table (IPv6 with family: ipv6)",
+		Params: []Param{
+			{Name: "gateway", Kind: KindString, Doc: "Expected next hop"},
+			{Name: "device", Kind: KindString, Doc: "Expected outgoing interface"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			c := &RouteChecker{device: o.Params.String("device"), family: o.Family}
+			if gateway := o.Params.String("gateway"); gateway != "" {
+				if c.gateway = net.ParseIP(gateway); c.gateway == nil {
+					return nil, fmt.Errorf("invalid gateway %q", gateway)
+				}
+				if o.Family == "" && c.gateway.To4() == nil {
+					c.family = FamilyIPv6
+				}
+			}
+			return c, nil
+		},
+	})
+
+	Register("pppoe", Factory{
+		Doc: "PPPoE session is established with an address",
+		Params: []Param{
+			{Name: "target", Kind: KindString, Doc: "ppp interface, default pppoe-wan"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			iface := cfg.Target
+			if iface == "" {
+				iface = "pppoe-wan"
+			}
+			return &PPPoEChecker{iface: iface, family: o.Family}, nil
+		},
+	})
+
+	Register("resource", Factory{
+		Doc: "Local resource pressure: conntrack table, available memory and load",
+		Params: []Param{
+			{Name: "max_conntrack_pct", Kind: KindFloat, Doc: "Fail at this conntrack table use, default 90"},
+			{Name: "min_mem_available_pct", Kind: KindFloat, Doc: "Fail below this share of memory available, default 10"},
+			{Name: "max_load", Kind: KindFloat, Doc: "Fail above this 1-minute load average per CPU"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			c := &ResourceChecker{
+				maxConntrackPct:    o.Params.Float("max_conntrack_pct"),
+				minMemAvailablePct: o.Params.Float("min_mem_available_pct"),
+				maxLoad:            o.Params.Float("max_load"),
+			}
+			if c.maxConntrackPct < 0 || c.maxConntrackPct > 100 || c.minMemAvailablePct < 0 || c.minMemAvailablePct > 100 {
+				return nil, fmt.Errorf("max_conntrack_pct and min_mem_available_pct must be between 0 and 100")
+			}
+			if c.maxLoad < 0 {
+				return nil, fmt.Errorf("max_load cannot be negative")
+			}
+			if c.maxConntrackPct == 0 {
+				c.maxConntrackPct = 90
+			}
+			if c.minMemAvailablePct == 0 {
+				c.minMemAvailablePct = 10
+			}
+			return c, nil
+		},
+	})
+
+	Register("process", Factory{
+		Doc: "Local daemon is running, optionally listening on a TCP port",
+		Params: []Param{
+			{Name: "target", Kind: KindString, Doc: "Process name, e.g. sing-box"},
+			{Name: "pidfile", Kind: KindString, Doc: "File holding the process ID"},
+			{Name: "cmdline", Kind: KindString, Doc: "Regex the command line must match"},
+			{Name: "listen_port", Kind: KindInt, Doc: "TCP port that must be listening"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return newProcessChecker(cfg.Target, o.Params)
+		},
+	})
+
+	Register("script", Factory{
+		Doc: "Root-owned executable; exit 0 passes, optional JSON on stdout",
+		Params: []Param{
+			{Name: "command", Kind: KindString, Required: true, Doc: "Absolute path of the executable"},
+			{Name: "args", Kind: KindStrings, Doc: "Arguments"},
+		},
+		New: func(cfg config.CheckConfig, o Options) (Checker, error) {
+			return newScriptChecker(cfg, o.Timeout)
+		},
+	})
+}
@@ -3,10 +3,12 @@ package checks
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"net/http"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/zczy-k/FloatingGateway/internal/config"
@@ -22,6 +24,14 @@ type Result struct {
 	ErrorCode string        `json:"error_code,omitempty"`
 	Message   string        `json:"message,omitempty"`
 	Duration  time.Duration `json:"-"`
+	AgeMs     int64         `json:"age_ms,omitempty"` // Checks with their own interval: time since the result was taken
+
+	// Ping statistics, measured when Sent is set
+	Sent     int     `json:"sent,omitempty"`
+	LossPct  float64 `json:"loss_pct,omitempty"`
+	RTTMinMs float64 `json:"rtt_min_ms,omitempty"`
+	RTTAvgMs float64 `json:"rtt_avg_ms,omitempty"`
+	RTTMaxMs float64 `json:"rtt_max_ms,omitempty"`
 }
 
 // Checker is the interface for health checks.
@@ -31,51 +41,84 @@ type Checker interface {
 	Target() string
 }
 
-// NewChecker creates a checker from config.
+// Address families accepted by CheckConfig.Family.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// network appends the family suffix to a base network ("tcp" -> "tcp6").
+func network(base, family string) string {
+	switch family {
+	case FamilyIPv4:
+		return base + "4"
+	case FamilyIPv6:
+		return base + "6"
+	}
+	return base
+}
+
+// NewChecker creates a checker from config, using the factory
+// registered for its type.
 func NewChecker(cfg config.CheckConfig) (Checker, error) {
-	timeout := time.Duration(cfg.Timeout) * time.Second
-	if timeout == 0 {
-		timeout = 5 * time.Second
+	factory, ok := Lookup(cfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown check type: %s", cfg.Type)
 	}
 
-	switch cfg.Type {
-	case "ping":
-		if cfg.Target == "" {
-			return nil, fmt.Errorf("ping check requires target")
-		}
-		return &PingChecker{target: cfg.Target, timeout: timeout}, nil
-	
-	case "dns":
-		if cfg.Resolver == "" || cfg.Domain == "" {
-			return nil, fmt.Errorf("dns check requires resolver and domain")
-		}
-		return &DNSChecker{resolver: cfg.Resolver, domain: cfg.Domain, timeout: timeout}, nil
-	
-	case "tcp":
-		if cfg.Target == "" || cfg.Port == 0 {
-			return nil, fmt.Errorf("tcp check requires target and port")
+	timeout := cfg.GetTimeout()
+
+	family := cfg.Family
+	if family != "" && family != FamilyIPv4 && family != FamilyIPv6 {
+		return nil, fmt.Errorf("invalid family %q: must be 'ipv4' or 'ipv6'", family)
+	}
+
+	source, err := newSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var proxy *url.URL
+	if cfg.Proxy != "" {
+		if !factory.HasParam("proxy") {
+			return nil, fmt.Errorf("proxy is not supported by %s checks", cfg.Type)
 		}
-		return &TCPChecker{target: cfg.Target, port: cfg.Port, timeout: timeout}, nil
-	
-	case "http":
-		url := cfg.URL
-		if url == "" && cfg.Target != "" {
-			url = cfg.Target
+		if proxy, err = parseProxy(cfg.Proxy); err != nil {
+			return nil, err
 		}
-		if url == "" {
-			return nil, fmt.Errorf("http check requires url or target")
+	}
+
+	// A source address implies its family
+	if family == "" && source.IP != nil {
+		family = FamilyIPv6
+		if source.IP.To4() != nil {
+			family = FamilyIPv4
 		}
-		return &HTTPChecker{url: url, timeout: timeout}, nil
-	
-	default:
-		return nil, fmt.Errorf("unknown check type: %s", cfg.Type)
 	}
+
+	params, err := factory.params(cfg.Type, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory.New(cfg, Options{
+		Timeout: timeout,
+		Family:  family,
+		Source:  source,
+		Proxy:   proxy,
+		Params:  params,
+	})
 }
 
-// PingChecker performs ICMP ping checks.
+// PingChecker performs ICMP ping checks with a native ICMP socket,
+// falling back to the system ping command if none can be opened.
 type PingChecker struct {
-	target  string
-	timeout time.Duration
+	target     string
+	timeout    time.Duration
+	family     string
+	count      int
+	interval   time.Duration
+	maxLossPct float64 // 0 = fail only if every probe is lost
+	source     Source
 }
 
 func (c *PingChecker) Type() string   { return "ping" }
@@ -88,17 +131,95 @@ func (c *PingChecker) Check(ctx context.Context) *Result {
 		Target: c.target,
 	}
 
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	ip, err := resolvePingTarget(timeoutCtx, c.target, c.family)
+	if err != nil {
+		result.ErrorCode = "PING_RESOLVE_FAILED"
+		result.Message = fmt.Sprintf("resolve %s: %v", c.target, err)
+		return result
+	}
+
+	conn, err := listenICMP(ip.To4() == nil, c.source)
+	if errors.Is(err, errNoICMPSocket) {
+		// No CAP_NET_RAW and ping sockets not allowed for our group
+		return c.systemPing(ctx, ip.String(), start)
+	}
+	if err != nil {
+		result.ErrorCode = "PING_SOURCE_FAILED"
+		result.Message = fmt.Sprintf("open ICMP socket (%s): %v", c.source, err)
+		return result
+	}
+	defer conn.Close()
+
+	stats, err := echo(timeoutCtx, conn, ip, c.count, c.interval)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.ErrorCode = "PING_FAILED"
+		result.Message = err.Error()
+		return result
+	}
+
+	min, avg, max := stats.minAvgMax()
+	result.Sent = stats.sent
+	result.LossPct = stats.lossPct()
+	result.RTTMinMs = durationMs(min)
+	result.RTTAvgMs = durationMs(avg)
+	result.RTTMaxMs = durationMs(max)
+	result.LatencyMs = avg.Milliseconds()
+
+	switch {
+	case stats.received == 0:
+		result.ErrorCode = "PING_NO_REPLY"
+		result.Message = fmt.Sprintf("no reply from %s (%d sent)", ip, stats.sent)
+	case c.maxLossPct > 0 && result.LossPct > c.maxLossPct:
+		result.ErrorCode = "PING_LOSS"
+		result.Message = fmt.Sprintf("%.0f%% loss to %s exceeds %.0f%%", result.LossPct, ip, c.maxLossPct)
+	default:
+		result.OK = true
+		result.Message = fmt.Sprintf("%d/%d replies, rtt min/avg/max %.1f/%.1f/%.1f ms",
+			stats.received, stats.sent, result.RTTMinMs, result.RTTAvgMs, result.RTTMaxMs)
+	}
+	return result
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// systemPing sends a single echo request with the system ping command.
+func (c *PingChecker) systemPing(ctx context.Context, target string, start time.Time) *Result {
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.target,
+	}
+
 	// Use system ping command
 	var args []string
 	// Detect ping version (Linux vs BSD/macOS)
 	if exec.CommandExists("ping") {
 		// Try Linux style first (-c count, -W timeout in seconds)
 		// We also add -n to avoid DNS resolution during ping
-		args = []string{"-c", "1", "-W", fmt.Sprintf("%d", int(c.timeout.Seconds())), "-n", c.target}
+		args = []string{"-c", "1", "-W", fmt.Sprintf("%d", int(c.timeout.Seconds())), "-n", target}
+		if c.source.Iface != "" {
+			args = append([]string{"-I", c.source.Iface}, args...)
+		} else if c.source.IP != nil {
+			args = append([]string{"-I", c.source.IP.String()}, args...)
+		}
+		if c.source.Mark != 0 {
+			args = append([]string{"-m", fmt.Sprintf("%d", c.source.Mark)}, args...)
+		}
+		switch c.family {
+		case FamilyIPv4:
+			args = append([]string{"-4"}, args...)
+		case FamilyIPv6:
+			args = append([]string{"-6"}, args...)
+		}
 	} else {
 		result.OK = false
 		result.ErrorCode = "PING_CMD_NOT_FOUND"
-		result.Message = "system ping command not found"
+		result.Message = "no ICMP socket available and system ping command not found"
 		return result
 	}
 
@@ -111,22 +232,16 @@ func (c *PingChecker) Check(ctx context.Context) *Result {
 
 	if cmdResult.Success() {
 		result.OK = true
-		result.Message = "ping successful"
+		result.Message = "ping successful (system ping)"
 		// Try to extract RTT from output
-		// We use a more robust regex-like parsing for different ping formats
 		output := cmdResult.Stdout
-		if strings.Contains(output, "time=") {
-			// Extract value between "time=" and " ms"
-			idx := strings.Index(output, "time=")
-			if idx != -1 {
-				sub := output[idx+5:]
-				endIdx := strings.Index(sub, " ")
-				if endIdx != -1 {
-					var rtt float64
-					fmt.Sscanf(sub[:endIdx], "%f", &rtt)
-					if rtt > 0 {
-						result.LatencyMs = int64(rtt)
-					}
+		if idx := strings.Index(output, "time="); idx != -1 {
+			sub := output[idx+5:]
+			if endIdx := strings.IndexAny(sub, " m"); endIdx != -1 {
+				var rtt float64
+				if _, err := fmt.Sscanf(sub[:endIdx], "%f", &rtt); err == nil {
+					result.LatencyMs = int64(rtt)
+					result.RTTMinMs, result.RTTAvgMs, result.RTTMaxMs = rtt, rtt, rtt
 				}
 			}
 		}
@@ -134,8 +249,12 @@ func (c *PingChecker) Check(ctx context.Context) *Result {
 		// Fallback for some busybox versions where -W might not be supported or behaves differently
 		if cmdResult.ExitCode != 0 && strings.Contains(cmdResult.Stderr, "invalid option") {
 			// Retry with simpler args
-			args = []string{"-c", "1", c.target}
-			cmdResult = exec.Run(timeoutCtx, "ping", args...)
+			name := "ping"
+			if c.family == FamilyIPv6 && exec.CommandExists("ping6") {
+				name = "ping6"
+			}
+			args = []string{"-c", "1", target}
+			cmdResult = exec.Run(timeoutCtx, name, args...)
 			if cmdResult.Success() {
 				result.OK = true
 				return result
@@ -143,6 +262,7 @@ func (c *PingChecker) Check(ctx context.Context) *Result {
 		}
 
 		result.OK = false
+		result.LossPct = 100
 		result.ErrorCode = "PING_FAILED"
 		result.Message = fmt.Sprintf("ping failed: %s", cmdResult.Combined())
 	}
@@ -150,64 +270,38 @@ func (c *PingChecker) Check(ctx context.Context) *Result {
 	return result
 }
 
-// DNSChecker performs DNS resolution checks.
-type DNSChecker struct {
-	resolver string
-	domain   string
-	timeout  time.Duration
-}
-
-func (c *DNSChecker) Type() string   { return "dns" }
-func (c *DNSChecker) Target() string { return fmt.Sprintf("%s@%s", c.domain, c.resolver) }
-
-func (c *DNSChecker) Check(ctx context.Context) *Result {
-	start := time.Now()
-	result := &Result{
-		Type:   c.Type(),
-		Target: c.Target(),
-	}
-
-	// Use custom resolver
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{Timeout: c.timeout}
-			return d.DialContext(ctx, "udp", net.JoinHostPort(c.resolver, "53"))
-		},
-	}
-
-	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
-	defer cancel()
-
-	addrs, err := resolver.LookupHost(timeoutCtx, c.domain)
-	result.Duration = time.Since(start)
-	result.LatencyMs = result.Duration.Milliseconds()
-
-	if err != nil {
-		result.OK = false
-		result.ErrorCode = "DNS_FAILED"
-		result.Message = fmt.Sprintf("DNS lookup failed: %v", err)
-	} else if len(addrs) == 0 {
-		result.OK = false
-		result.ErrorCode = "DNS_NO_RESULT"
-		result.Message = "DNS lookup returned no addresses"
-	} else {
-		result.OK = true
-		result.Message = fmt.Sprintf("resolved to %s", strings.Join(addrs, ", "))
-	}
-
-	return result
-}
-
-// TCPChecker performs TCP connection checks.
+// TCPChecker performs TCP connection checks, optionally exchanging a
+// payload so that a local transparent proxy accepting the connection on
+// behalf of a dead upstream doesn't count as success.
 type TCPChecker struct {
 	target  string
 	port    int
 	timeout time.Duration
+	family  string
+	source  Source
+	proxy   *url.URL // Tunnel through this proxy if set
+	send    string   // Written after connecting
+	expect  string   // Must appear in what the server sends back
+}
+
+// maxExpectBytes bounds how much is read while waiting for expect.
+const maxExpectBytes = 4 << 10
+
+func newTCPChecker(cfg config.CheckConfig, timeout time.Duration, family string, source Source, proxy *url.URL) *TCPChecker {
+	return &TCPChecker{
+		target:  cfg.Target,
+		port:    cfg.Port,
+		timeout: timeout,
+		family:  family,
+		source:  source,
+		proxy:   proxy,
+		send:    cfg.Send,
+		expect:  cfg.Expect,
+	}
 }
 
 func (c *TCPChecker) Type() string   { return "tcp" }
-func (c *TCPChecker) Target() string { return fmt.Sprintf("%s:%d", c.target, c.port) }
+func (c *TCPChecker) Target() string { return net.JoinHostPort(c.target, fmt.Sprintf("%d", c.port)) }
 
 func (c *TCPChecker) Check(ctx context.Context) *Result {
 	start := time.Now()
@@ -216,96 +310,191 @@ func (c *TCPChecker) Check(ctx context.Context) *Result {
 		Target: c.Target(),
 	}
 
-	dialer := net.Dialer{Timeout: c.timeout}
 	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", c.Target())
+	var conn net.Conn
+	var err error
+	if c.proxy != nil {
+		conn, err = dialProxy(timeoutCtx, c.proxy, c.source, "tcp", c.Target(), c.timeout)
+	} else {
+		conn, err = c.source.Dial(timeoutCtx, network("tcp", c.family), c.Target(), c.timeout)
+	}
 	result.Duration = time.Since(start)
 	result.LatencyMs = result.Duration.Milliseconds()
 
 	if err != nil {
 		result.OK = false
 		result.ErrorCode = "TCP_FAILED"
+		if code, ok := proxyErrorCode(err); ok {
+			result.ErrorCode = code
+		}
 		result.Message = fmt.Sprintf("TCP connect failed: %v", err)
-	} else {
-		conn.Close()
+		return result
+	}
+	defer conn.Close()
+
+	if c.send == "" && c.expect == "" {
 		result.OK = true
 		result.Message = "TCP connect successful"
+		return result
 	}
 
+	if deadline, ok := timeoutCtx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	code, msg := c.exchange(conn)
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	if code != "" {
+		result.OK = false
+		result.ErrorCode = code
+		result.Message = msg
+		return result
+	}
+	result.OK = true
+	result.Message = msg
 	return result
 }
 
-// HTTPChecker performs HTTP request checks.
-type HTTPChecker struct {
-	url     string
-	timeout time.Duration
-}
-
-func (c *HTTPChecker) Type() string   { return "http" }
-func (c *HTTPChecker) Target() string { return c.url }
-
-func (c *HTTPChecker) Check(ctx context.Context) *Result {
-	start := time.Now()
-	result := &Result{
-		Type:   c.Type(),
-		Target: c.url,
+// exchange writes the send payload and reads until expect shows up. It
+// returns an error code, empty on success, and a message.
+func (c *TCPChecker) exchange(conn net.Conn) (string, string) {
+	if c.send != "" {
+		if _, err := conn.Write([]byte(c.send)); err != nil {
+			return "TCP_SEND_FAILED", fmt.Sprintf("TCP send failed: %v", err)
+		}
+	}
+	if c.expect == "" {
+		return "", fmt.Sprintf("TCP connect successful, sent %d bytes", len(c.send))
 	}
 
-	client := &http.Client{
-		Timeout: c.timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 3 {
-				return fmt.Errorf("too many redirects")
+	var got []byte
+	buf := make([]byte, 512)
+	for len(got) < maxExpectBytes {
+		n, err := conn.Read(buf)
+		got = append(got, buf[:n]...)
+		if strings.Contains(string(got), c.expect) {
+			return "", fmt.Sprintf("TCP response matched %q", c.expect)
+		}
+		if err != nil {
+			if len(got) == 0 {
+				return "TCP_NO_RESPONSE", fmt.Sprintf("no response before %v", err)
 			}
-			return nil
-		},
+			break
+		}
 	}
+	return "TCP_EXPECT_MISMATCH", fmt.Sprintf("TCP response %q does not contain %q", truncate(string(got), 64), c.expect)
+}
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(timeoutCtx, "GET", c.url, nil)
-	if err != nil {
-		result.OK = false
-		result.ErrorCode = "HTTP_INVALID_REQUEST"
-		result.Message = fmt.Sprintf("invalid request: %v", err)
-		return result
+// truncate shortens s to at most n bytes for messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[:n] + "..."
+}
 
-	req.Header.Set("User-Agent", "gateway-agent/1.0")
+// ProxyChecker checks that the local proxy can reach a target, such as a
+// bypass router's Clash or sing-box port for the international link.
+type ProxyChecker struct {
+	inner Checker // HTTP or TCP checker dialing through the proxy
+	proxy *url.URL
+}
 
-	resp, err := client.Do(req)
-	result.Duration = time.Since(start)
-	result.LatencyMs = result.Duration.Milliseconds()
+func (c *ProxyChecker) Type() string { return "proxy" }
+func (c *ProxyChecker) Target() string {
+	return fmt.Sprintf("%s via %s", c.inner.Target(), proxyName(c.proxy))
+}
 
-	if err != nil {
-		result.OK = false
-		result.ErrorCode = "HTTP_FAILED"
-		result.Message = fmt.Sprintf("HTTP request failed: %v", err)
-		return result
+func (c *ProxyChecker) Check(ctx context.Context) *Result {
+	result := c.inner.Check(ctx)
+	result.Type = c.Type()
+	result.Target = c.Target()
+	return result
+}
+
+// roundGrace is how much earlier than the round deadline the checks' own
+// context expires, so a check that runs until its deadline (a ping
+// waiting for a lost reply) still reports its result.
+const roundGrace = 250 * time.Millisecond
+
+// RunAll runs the checkers concurrently, at most workers at a time, and
+// returns their results in order. It returns by the time ctx is done:
+// checks that haven't finished by then are reported as timed out, and
+// checks not yet started are skipped.
+func RunAll(ctx context.Context, checkers []Checker, workers int) []*Result {
+	if workers < 1 {
+		workers = 1
 	}
-	defer resp.Body.Close()
 
-	// Consider 2xx and 3xx as success
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		result.OK = true
-		result.Message = fmt.Sprintf("HTTP %d", resp.StatusCode)
-	} else {
-		result.OK = false
-		result.ErrorCode = fmt.Sprintf("HTTP_%d", resp.StatusCode)
-		result.Message = fmt.Sprintf("HTTP %d %s", resp.StatusCode, resp.Status)
+	checkCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		grace := min(roundGrace, time.Until(deadline)/10)
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithDeadline(ctx, deadline.Add(-grace))
+		defer cancel()
 	}
 
-	return result
-}
+	type done struct {
+		i      int
+		result *Result
+	}
+	jobs := make(chan int, len(checkers))
+	for i := range checkers {
+		jobs <- i
+	}
+	close(jobs)
+	// Buffered so checks finishing after the deadline never block
+	finished := make(chan done, len(checkers))
+	started := make([]atomic.Bool, len(checkers))
+
+	for w := 0; w < workers && w < len(checkers); w++ {
+		go func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				started[i].Store(true)
+				finished <- done{i, checkers[i].Check(checkCtx)}
+			}
+		}()
+	}
 
-// RunAll runs all checkers and returns results.
-func RunAll(ctx context.Context, checkers []Checker) []*Result {
 	results := make([]*Result, len(checkers))
-	for i, checker := range checkers {
-		results[i] = checker.Check(ctx)
+collect:
+	for range checkers {
+		select {
+		case d := <-finished:
+			results[d.i] = d.result
+		case <-ctx.Done():
+			break collect
+		}
+	}
+	// select picks at random when both are ready, so keep results that
+	// arrived by the deadline rather than reporting them as timeouts
+drain:
+	for {
+		select {
+		case d := <-finished:
+			results[d.i] = d.result
+		default:
+			break drain
+		}
+	}
+
+	for i, r := range results {
+		if r == nil {
+			results[i] = &Result{
+				Type:      checkers[i].Type(),
+				Target:    checkers[i].Target(),
+				ErrorCode: "CHECK_TIMEOUT",
+				Message:   "check did not finish within the round deadline",
+			}
+			if !started[i].Load() {
+				results[i].Message = "check did not start before the round deadline, all workers were busy"
+			}
+		}
 	}
 	return results
 }
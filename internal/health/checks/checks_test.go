@@ -0,0 +1,73 @@
+package checks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stubChecker returns its result once release is closed, if set, and
+// cancels the round when done, if set.
+type stubChecker struct {
+	target  string
+	release chan struct{}
+	done    context.CancelFunc
+}
+
+func (c *stubChecker) Check(ctx context.Context) *Result {
+	if c.release != nil {
+		<-c.release
+	}
+	if c.done != nil {
+		c.done()
+	}
+	return &Result{Type: c.Type(), Target: c.target, OK: true}
+}
+
+func (c *stubChecker) Type() string   { return "stub" }
+func (c *stubChecker) Target() string { return c.target }
+
+func TestRunAll_FastAndSlow(t *testing.T) {
+	// The fast check ends the round as it finishes, so its result and the
+	// deadline are ready at the same time; it must never become a timeout.
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		checkers := []Checker{
+			&stubChecker{target: "slow", release: release},
+			&stubChecker{target: "fast", done: cancel},
+		}
+
+		results := RunAll(ctx, checkers, 2)
+		close(release)
+
+		if !results[1].OK {
+			t.Fatalf("run %d: fast check = %s %q, want success", i, results[1].ErrorCode, results[1].Message)
+		}
+		if results[0].ErrorCode != "CHECK_TIMEOUT" {
+			t.Fatalf("run %d: slow check code = %q, want CHECK_TIMEOUT", i, results[0].ErrorCode)
+		}
+	}
+}
+
+// deadlineChecker waits until its context expires and then reports a
+// measured result, like a ping waiting for a lost reply.
+type deadlineChecker struct{}
+
+func (deadlineChecker) Check(ctx context.Context) *Result {
+	<-ctx.Done()
+	return &Result{Type: "stub", Target: "until-deadline", OK: true, Sent: 3, LossPct: 33}
+}
+
+func (deadlineChecker) Type() string   { return "stub" }
+func (deadlineChecker) Target() string { return "until-deadline" }
+
+func TestRunAll_CheckRunningUntilDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	results := RunAll(ctx, []Checker{deadlineChecker{}}, 1)
+	if r := results[0]; !r.OK || r.LossPct != 33 {
+		t.Errorf("result = %s %q, want the check's own result", r.ErrorCode, r.Message)
+	}
+}
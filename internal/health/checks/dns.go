@@ -0,0 +1,580 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// DNS record types accepted by CheckConfig.RecordType.
+var dnsTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+}
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// DNS response codes that get their own ErrorCode.
+const (
+	dnsRcodeServFail = 2
+	dnsRcodeNXDomain = 3
+	dnsRcodeRefused  = 5
+)
+
+// DNSChecker performs DNS resolution checks over UDP, TCP, DNS over TLS
+// or DNS over HTTPS, and validates the answer so that a poisoned
+// resolver counts as a failure rather than a success.
+type DNSChecker struct {
+	name      string // Resolver as configured, for Target
+	resolver  string // host:port, or the DoH URL
+	transport string
+	domain    string
+	qtypes    []uint16
+	expect    []netip.Prefix // Every address must fall inside one
+	reject    []netip.Prefix // No address may fall inside one
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	family    string
+	source    Source
+}
+
+func newDNSChecker(cfg config.CheckConfig, timeout time.Duration, family string, source Source) (*DNSChecker, error) {
+	c := &DNSChecker{
+		name:      cfg.Resolver,
+		transport: cfg.Transport,
+		domain:    cfg.Domain,
+		timeout:   timeout,
+		family:    family,
+		source:    source,
+	}
+	if c.transport == "" {
+		c.transport = config.DNSTransportUDP
+		if strings.HasPrefix(cfg.Resolver, "https://") {
+			c.transport = config.DNSTransportHTTPS
+		}
+	}
+
+	switch c.transport {
+	case config.DNSTransportUDP, config.DNSTransportTCP:
+		c.resolver = withDefaultPort(cfg.Resolver, "53")
+	case config.DNSTransportTLS:
+		c.resolver = withDefaultPort(cfg.Resolver, "853")
+	case config.DNSTransportHTTPS:
+		c.resolver = cfg.Resolver
+		if !strings.HasPrefix(c.resolver, "https://") {
+			c.resolver = "https://" + withDefaultPort(cfg.Resolver, "443") + "/dns-query"
+		}
+	default:
+		return nil, fmt.Errorf("invalid transport %q: must be 'udp', 'tcp', 'tls' or 'https'", cfg.Transport)
+	}
+
+	switch {
+	case cfg.RecordType != "":
+		qtype, ok := dnsTypes[strings.ToUpper(cfg.RecordType)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported record_type %q", cfg.RecordType)
+		}
+		c.qtypes = []uint16{qtype}
+	case family == FamilyIPv4:
+		c.qtypes = []uint16{dnsTypeA}
+	case family == FamilyIPv6:
+		c.qtypes = []uint16{dnsTypeAAAA}
+	default:
+		c.qtypes = []uint16{dnsTypeA, dnsTypeAAAA}
+	}
+
+	for _, s := range cfg.ExpectCIDRs {
+		p, err := config.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("expect_cidrs: %w", err)
+		}
+		c.expect = append(c.expect, p)
+	}
+	for _, s := range cfg.RejectIPs {
+		p, err := config.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("reject_ips: %w", err)
+		}
+		c.reject = append(c.reject, p)
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if c.transport == config.DNSTransportTLS {
+		// tls.Client needs a name to verify unless verification is off
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			host, _, _ := net.SplitHostPort(c.resolver)
+			tlsConfig.ServerName = host
+		}
+	}
+	c.tlsConfig = tlsConfig
+
+	return c, nil
+}
+
+// withDefaultPort appends port to a resolver given without one. Bare IPv6
+// addresses are accepted without brackets.
+func withDefaultPort(resolver, port string) string {
+	if net.ParseIP(resolver) != nil {
+		return net.JoinHostPort(resolver, port)
+	}
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(resolver, port)
+}
+
+func (c *DNSChecker) Type() string { return "dns" }
+
+func (c *DNSChecker) Target() string {
+	if c.transport == config.DNSTransportUDP || c.transport == config.DNSTransportHTTPS {
+		return fmt.Sprintf("%s@%s", c.domain, c.name)
+	}
+	return fmt.Sprintf("%s@%s://%s", c.domain, c.transport, c.resolver)
+}
+
+func (c *DNSChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var answers []dnsRecord
+	for _, qtype := range c.qtypes {
+		resp, err := c.query(timeoutCtx, qtype)
+		if err != nil {
+			result.Duration = time.Since(start)
+			result.LatencyMs = result.Duration.Milliseconds()
+			result.ErrorCode = dnsErrorCode(err)
+			result.Message = fmt.Sprintf("DNS lookup failed: %v", err)
+			return result
+		}
+		switch resp.rcode {
+		case 0:
+		case dnsRcodeNXDomain:
+			result.ErrorCode = "DNS_NXDOMAIN"
+		case dnsRcodeServFail:
+			result.ErrorCode = "DNS_SERVFAIL"
+		case dnsRcodeRefused:
+			result.ErrorCode = "DNS_REFUSED"
+		default:
+			result.ErrorCode = "DNS_FAILED"
+		}
+		if result.ErrorCode != "" {
+			result.Duration = time.Since(start)
+			result.LatencyMs = result.Duration.Milliseconds()
+			result.Message = fmt.Sprintf("DNS lookup failed: %s", rcodeName(resp.rcode))
+			return result
+		}
+		for _, rr := range resp.answers {
+			if rr.typ == qtype {
+				answers = append(answers, rr)
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+
+	if len(answers) == 0 {
+		result.ErrorCode = "DNS_NO_RESULT"
+		result.Message = "DNS lookup returned no records"
+		return result
+	}
+
+	values := make([]string, len(answers))
+	for i, rr := range answers {
+		values[i] = rr.value
+		if !rr.addr.IsValid() {
+			continue
+		}
+		if p, ok := matchPrefix(c.reject, rr.addr); ok {
+			result.ErrorCode = "DNS_POISONED"
+			result.Message = fmt.Sprintf("answer %s matches reject_ips %s", rr.addr, p)
+			return result
+		}
+		if len(c.expect) > 0 {
+			if _, ok := matchPrefix(c.expect, rr.addr); !ok {
+				result.ErrorCode = "DNS_UNEXPECTED_ANSWER"
+				result.Message = fmt.Sprintf("answer %s is outside expect_cidrs", rr.addr)
+				return result
+			}
+		}
+	}
+
+	result.OK = true
+	result.Message = fmt.Sprintf("resolved to %s", strings.Join(values, ", "))
+	return result
+}
+
+func matchPrefix(prefixes []netip.Prefix, addr netip.Addr) (netip.Prefix, bool) {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return p, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// query sends one question over the configured transport.
+func (c *DNSChecker) query(ctx context.Context, qtype uint16) (*dnsResponse, error) {
+	var id uint16
+	if c.transport != config.DNSTransportHTTPS {
+		// DoH uses ID 0 so responses stay cacheable (RFC 8484 section 4.1)
+		var b [2]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		id = binary.BigEndian.Uint16(b[:])
+	}
+	msg, err := buildDNSQuery(id, c.domain, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	switch c.transport {
+	case config.DNSTransportUDP:
+		raw, err = c.exchangeUDP(ctx, msg, id)
+	case config.DNSTransportHTTPS:
+		raw, err = c.exchangeHTTPS(ctx, msg)
+	default:
+		raw, err = c.exchangeStream(ctx, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := parseDNSResponse(raw, id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.truncated && c.transport == config.DNSTransportUDP {
+		// Answer too large for UDP: retry over TCP like a stub resolver
+		if raw, err = c.exchangeStream(ctx, msg); err != nil {
+			return nil, err
+		}
+		return parseDNSResponse(raw, id)
+	}
+	return resp, nil
+}
+
+func (c *DNSChecker) exchangeUDP(ctx context.Context, msg []byte, id uint16) ([]byte, error) {
+	conn, err := c.source.Dial(ctx, network("udp", c.family), c.resolver, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Skip stray datagrams, e.g. late answers to an earlier query
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// exchangeStream sends a length-prefixed query over TCP, or DNS over TLS
+// when configured.
+func (c *DNSChecker) exchangeStream(ctx context.Context, msg []byte) ([]byte, error) {
+	conn, err := c.source.Dial(ctx, network("tcp", c.family), c.resolver, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if c.transport == config.DNSTransportTLS {
+		tlsConn := tls.Client(conn, c.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, &dnsTLSError{err}
+		}
+		conn = tlsConn
+	}
+
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err := conn.Write(append(frame, msg...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeHTTPS posts the query as application/dns-message (RFC 8484).
+func (c *DNSChecker) exchangeHTTPS(ctx context.Context, msg []byte) ([]byte, error) {
+	client := &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return c.source.Dial(ctx, network("tcp", c.family), addr, c.timeout)
+			},
+			TLSClientConfig:     c.tlsConfig,
+			TLSHandshakeTimeout: c.timeout,
+			ForceAttemptHTTP2:   true,
+			DisableKeepAlives:   true,
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.resolver, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	req.Header.Set("User-Agent", "gateway-agent/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if httpErrorCode(err) == "HTTP_TLS_CERT_INVALID" || httpErrorCode(err) == "HTTP_TLS_FAILED" {
+			return nil, &dnsTLSError{err}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned HTTP %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+// dnsTLSError marks a failed TLS handshake with a DoT or DoH server.
+type dnsTLSError struct{ err error }
+
+func (e *dnsTLSError) Error() string { return "TLS: " + e.err.Error() }
+func (e *dnsTLSError) Unwrap() error { return e.err }
+
+// dnsErrorCode classifies a failed exchange.
+func dnsErrorCode(err error) string {
+	var (
+		tlsErr  *dnsTLSError
+		certErr *tls.CertificateVerificationError
+		unknown x509.UnknownAuthorityError
+		netErr  net.Error
+	)
+	switch {
+	case errors.As(err, &tlsErr), errors.As(err, &certErr), errors.As(err, &unknown):
+		return "DNS_TLS_FAILED"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "DNS_TIMEOUT"
+	}
+	return "DNS_FAILED"
+}
+
+func rcodeName(rcode int) string {
+	switch rcode {
+	case dnsRcodeServFail:
+		return "SERVFAIL"
+	case dnsRcodeNXDomain:
+		return "NXDOMAIN"
+	case dnsRcodeRefused:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("rcode %d", rcode)
+}
+
+// dnsRecord is one answer record. addr is set for A and AAAA records.
+type dnsRecord struct {
+	typ   uint16
+	value string
+	addr  netip.Addr
+}
+
+type dnsResponse struct {
+	rcode     int
+	truncated bool
+	answers   []dnsRecord
+}
+
+// buildDNSQuery encodes a recursive query for one name and type.
+func buildDNSQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)      // QDCOUNT
+
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("domain %q is too long", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+	return msg, nil
+}
+
+var errDNSMalformed = errors.New("malformed DNS response")
+
+// parseDNSResponse decodes the header and answer section of a response.
+func parseDNSResponse(msg []byte, id uint16) (*dnsResponse, error) {
+	if len(msg) < 12 {
+		return nil, errDNSMalformed
+	}
+	if binary.BigEndian.Uint16(msg) != id {
+		return nil, fmt.Errorf("DNS response ID mismatch")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return nil, fmt.Errorf("DNS response is not a reply")
+	}
+	resp := &dnsResponse{
+		rcode:     int(flags & 0x000f),
+		truncated: flags&0x0200 != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+
+	for i := 0; i < ancount; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next
+		if off+10 > len(msg) {
+			return nil, errDNSMalformed
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, errDNSMalformed
+		}
+		rdata := msg[off : off+rdlen]
+
+		rr := dnsRecord{typ: typ}
+		switch typ {
+		case dnsTypeA, dnsTypeAAAA:
+			addr, ok := netip.AddrFromSlice(rdata)
+			if !ok || (typ == dnsTypeA) != addr.Is4() {
+				return nil, errDNSMalformed
+			}
+			rr.addr = addr
+			rr.value = addr.String()
+		case dnsTypes["CNAME"], dnsTypes["NS"], dnsTypes["PTR"]:
+			if rr.value, _, err = readDNSName(msg, off); err != nil {
+				return nil, err
+			}
+		case dnsTypes["MX"]:
+			if rdlen < 3 {
+				return nil, errDNSMalformed
+			}
+			host, _, err := readDNSName(msg, off+2)
+			if err != nil {
+				return nil, err
+			}
+			rr.value = fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), host)
+		case dnsTypes["TXT"]:
+			var parts []string
+			for p := 0; p < len(rdata); {
+				l := int(rdata[p])
+				if p+1+l > len(rdata) {
+					return nil, errDNSMalformed
+				}
+				parts = append(parts, string(rdata[p+1:p+1+l]))
+				p += 1 + l
+			}
+			rr.value = fmt.Sprintf("%q", strings.Join(parts, ""))
+		}
+		resp.answers = append(resp.answers, rr)
+		off += rdlen
+	}
+	return resp, nil
+}
+
+// readDNSName decodes a possibly compressed name at off and returns it
+// with the offset just past it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for hops := 0; ; hops++ {
+		if off >= len(msg) || hops > 127 {
+			return "", 0, errDNSMalformed
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errDNSMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+l > len(msg) {
+				return "", 0, errDNSMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package checks
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// dnsReply builds a response to a query for example.com with the given
+// flags and answer records. Each answer is the raw record from its name
+// on; ptrQName (0xc00c) points at the question name.
+func dnsReply(t *testing.T, flags uint16, answers ...[]byte) []byte {
+	t.Helper()
+	msg, err := buildDNSQuery(0x1234, "example.com", dnsTypeA)
+	if err != nil {
+		t.Fatalf("buildDNSQuery: %v", err)
+	}
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(answers)))
+	for _, a := range answers {
+		msg = append(msg, a...)
+	}
+	return msg
+}
+
+var ptrQName = []byte{0xc0, 0x0c}
+
+// rr encodes a record of class IN with TTL 60 after name.
+func rr(name []byte, typ uint16, rdata []byte) []byte {
+	b := append([]byte{}, name...)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, 1)
+	b = binary.BigEndian.AppendUint32(b, 60)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	const reply = 0x8180 // QR, RD, RA
+
+	tests := []struct {
+		name      string
+		msg       func(t *testing.T) []byte
+		wantErr   error // Compared with errors.Is when wantMsg is empty
+		wantMsg   string
+		rcode     int
+		truncated bool
+		answers   []string
+	}{
+		{
+			name: "A",
+			msg: func(t *testing.T) []byte {
+				return dnsReply(t, reply, rr(ptrQName, dnsTypeA, []byte{93, 184, 216, 34}))
+			},
+			answers: []string{"93.184.216.34"},
+		},
+		{
+			name: "AAAA",
+			msg: func(t *testing.T) []byte {
+				return dnsReply(t, reply, rr(ptrQName, dnsTypeAAAA, []byte{0x26, 0x06, 0x28, 0, 0x02, 0x20, 0, 1, 0x2, 0x48, 0x18, 0x93, 0x25, 0xc8, 0x19, 0x46}))
+			},
+			answers: []string{"2606:2800:220:1:248:1893:25c8:1946"},
+		},
+		{
+			name: "CNAME chain with compressed target",
+			msg: func(t *testing.T) []byte {
+				// www + pointer to example.com in the question
+				target := []byte{3, 'w', 'w', 'w', 0xc0, 0x0c}
+				return dnsReply(t, reply,
+					rr(ptrQName, dnsTypes["CNAME"], target),
+					rr(ptrQName, dnsTypeA, []byte{10, 0, 0, 1}))
+			},
+			answers: []string{"www.example.com.", "10.0.0.1"},
+		},
+		{
+			name: "MX and TXT",
+			msg: func(t *testing.T) []byte {
+				mx := append([]byte{0, 10}, ptrQName...)
+				txt := []byte{5, 'h', 'e', 'l', 'l', 'o', 6, ' ', 'w', 'o', 'r', 'l', 'd'}
+				return dnsReply(t, reply,
+					rr(ptrQName, dnsTypes["MX"], mx),
+					rr(ptrQName, dnsTypes["TXT"], txt))
+			},
+			answers: []string{"10 example.com.", `"hello world"`},
+		},
+		{
+			name:  "NXDOMAIN",
+			msg:   func(t *testing.T) []byte { return dnsReply(t, reply|dnsRcodeNXDomain) },
+			rcode: dnsRcodeNXDomain,
+		},
+		{
+			name:  "SERVFAIL",
+			msg:   func(t *testing.T) []byte { return dnsReply(t, reply|dnsRcodeServFail) },
+			rcode: dnsRcodeServFail,
+		},
+		{
+			name:      "truncated flag",
+			msg:       func(t *testing.T) []byte { return dnsReply(t, reply|0x0200) },
+			truncated: true,
+		},
+		{
+			name: "pointer loop",
+			msg: func(t *testing.T) []byte {
+				// The answer name points at itself, just past the question
+				msg := dnsReply(t, reply)
+				self := uint16(len(msg)) | 0xc000
+				return dnsReply(t, reply, rr(binary.BigEndian.AppendUint16(nil, self), dnsTypeA, []byte{10, 0, 0, 1}))
+			},
+			wantErr: errDNSMalformed,
+		},
+		{
+			name: "truncated rdata",
+			msg: func(t *testing.T) []byte {
+				msg := dnsReply(t, reply, rr(ptrQName, dnsTypeA, []byte{10, 0, 0, 1}))
+				return msg[:len(msg)-2]
+			},
+			wantErr: errDNSMalformed,
+		},
+		{
+			name: "A record of the wrong length",
+			msg: func(t *testing.T) []byte {
+				return dnsReply(t, reply, rr(ptrQName, dnsTypeA, make([]byte, 16)))
+			},
+			wantErr: errDNSMalformed,
+		},
+		{
+			name: "TXT string past rdata",
+			msg: func(t *testing.T) []byte {
+				return dnsReply(t, reply, rr(ptrQName, dnsTypes["TXT"], []byte{9, 'x'}))
+			},
+			wantErr: errDNSMalformed,
+		},
+		{
+			name:    "short header",
+			msg:     func(t *testing.T) []byte { return []byte{0x12, 0x34, 0x81} },
+			wantErr: errDNSMalformed,
+		},
+		{
+			name: "ID mismatch",
+			msg: func(t *testing.T) []byte {
+				msg := dnsReply(t, reply)
+				msg[1]++
+				return msg
+			},
+			wantMsg: "ID mismatch",
+		},
+		{
+			name:    "not a reply",
+			msg:     func(t *testing.T) []byte { return dnsReply(t, 0x0100) },
+			wantMsg: "not a reply",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseDNSResponse(tt.msg(t), 0x1234)
+			switch {
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want %q", err, tt.wantMsg)
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatalf("parseDNSResponse: %v", err)
+			}
+
+			if resp.rcode != tt.rcode {
+				t.Errorf("rcode = %d, want %d", resp.rcode, tt.rcode)
+			}
+			if resp.truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", resp.truncated, tt.truncated)
+			}
+			var got []string
+			for _, a := range resp.answers {
+				got = append(got, a.value)
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.answers, "; ") {
+				t.Errorf("answers = %q, want %q", got, tt.answers)
+			}
+		})
+	}
+}
+
+func TestBuildDNSQuery(t *testing.T) {
+	msg, err := buildDNSQuery(0xbeef, "example.com.", dnsTypeAAAA)
+	if err != nil {
+		t.Fatalf("buildDNSQuery: %v", err)
+	}
+	want := "\xbe\xef\x01\x00\x00\x01\x00\x00\x00\x00\x00\x00" +
+		"\x07example\x03com\x00" + "\x00\x1c\x00\x01"
+	if string(msg) != want {
+		t.Errorf("query = %q, want %q", msg, want)
+	}
+
+	for _, name := range []string{"", "a..b", strings.Repeat("a", 64) + ".com", strings.Repeat("a.", 127) + "aa"} {
+		if _, err := buildDNSQuery(1, name, dnsTypeA); err == nil {
+			t.Errorf("buildDNSQuery(%q) succeeded, want error", name)
+		}
+	}
+}
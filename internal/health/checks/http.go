@@ -0,0 +1,257 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// maxBodyBytes bounds how much of a response body is read for matching.
+const maxBodyBytes = 64 << 10
+
+// HTTPChecker performs HTTP request checks.
+type HTTPChecker struct {
+	url     string
+	timeout time.Duration
+	family  string
+	source  Source
+	proxy   *url.URL // Tunnel through this proxy instead of the environment's
+
+	method          string
+	headers         map[string]string
+	expectStatus    []int // Empty means any 2xx/3xx
+	expectBody      string
+	expectBodyRegex *regexp.Regexp
+	followRedirects bool
+	tlsConfig       *tls.Config
+}
+
+func newHTTPChecker(cfg config.CheckConfig, rawURL string, timeout time.Duration, family string, source Source, proxy *url.URL) (*HTTPChecker, error) {
+	c := &HTTPChecker{
+		url:             rawURL,
+		timeout:         timeout,
+		family:          family,
+		source:          source,
+		proxy:           proxy,
+		method:          strings.ToUpper(cfg.Method),
+		headers:         cfg.Headers,
+		expectStatus:    cfg.ExpectStatus,
+		expectBody:      cfg.ExpectBody,
+		followRedirects: cfg.GetFollowRedirects(),
+	}
+	if c.method == "" {
+		c.method = http.MethodGet
+	}
+
+	if cfg.ExpectBodyRegex != "" {
+		re, err := regexp.Compile(cfg.ExpectBodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_body_regex: %w", err)
+		}
+		c.expectBodyRegex = re
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.tlsConfig = tlsConfig
+
+	return c, nil
+}
+
+// newTLSConfig builds the client TLS settings of a check, or nil to use
+// the defaults.
+func newTLSConfig(cfg config.CheckConfig) (*tls.Config, error) {
+	if cfg.TLSServerName == "" && !cfg.TLSSkipVerify && cfg.TLSCAFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file %s contains no PEM certificates", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+func (c *HTTPChecker) Type() string   { return "http" }
+func (c *HTTPChecker) Target() string { return c.url }
+
+func (c *HTTPChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.url,
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return c.source.Dial(ctx, network("tcp", c.family), addr, c.timeout)
+		},
+		TLSClientConfig:     c.tlsConfig,
+		TLSHandshakeTimeout: c.timeout,
+		DisableKeepAlives:   true,
+	}
+	if c.proxy != nil {
+		// Tunnel every request, plain HTTP included, so proxy failures
+		// surface as ProxyError for both proxy schemes
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialProxy(ctx, c.proxy, c.source, "tcp", addr, c.timeout)
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   c.timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !c.followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 3 {
+				return errTooManyRedirects
+			}
+			return nil
+		},
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(timeoutCtx, c.method, c.url, nil)
+	if err != nil {
+		result.OK = false
+		result.ErrorCode = "HTTP_INVALID_REQUEST"
+		result.Message = fmt.Sprintf("invalid request: %v", err)
+		return result
+	}
+
+	req.Header.Set("User-Agent", "gateway-agent/1.0")
+	for k, v := range c.headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+
+	if err != nil {
+		result.OK = false
+		result.ErrorCode = httpErrorCode(err)
+		result.Message = fmt.Sprintf("HTTP request failed: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	if !c.statusOK(resp.StatusCode) {
+		result.OK = false
+		result.ErrorCode = fmt.Sprintf("HTTP_%d", resp.StatusCode)
+		result.Message = fmt.Sprintf("HTTP %s", resp.Status)
+		if len(c.expectStatus) > 0 {
+			result.Message += fmt.Sprintf(", expected %v", c.expectStatus)
+		}
+		return result
+	}
+
+	if c.expectBody != "" || c.expectBodyRegex != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			result.OK = false
+			result.ErrorCode = "HTTP_BODY_READ_FAILED"
+			result.Message = fmt.Sprintf("read body: %v", err)
+			return result
+		}
+		if c.expectBody != "" && !strings.Contains(string(body), c.expectBody) {
+			result.OK = false
+			result.ErrorCode = "HTTP_BODY_MISMATCH"
+			result.Message = fmt.Sprintf("HTTP %d body does not contain %q", resp.StatusCode, c.expectBody)
+			return result
+		}
+		if c.expectBodyRegex != nil && !c.expectBodyRegex.Match(body) {
+			result.OK = false
+			result.ErrorCode = "HTTP_BODY_MISMATCH"
+			result.Message = fmt.Sprintf("HTTP %d body does not match /%s/", resp.StatusCode, c.expectBodyRegex)
+			return result
+		}
+	}
+
+	result.OK = true
+	result.Message = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	return result
+}
+
+// statusOK checks the status against expect_status, or 2xx/3xx by default.
+func (c *HTTPChecker) statusOK(code int) bool {
+	if len(c.expectStatus) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, want := range c.expectStatus {
+		if code == want {
+			return true
+		}
+	}
+	return false
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// httpErrorCode classifies a failed request.
+func httpErrorCode(err error) string {
+	var (
+		dnsErr      *net.DNSError
+		certErr     *tls.CertificateVerificationError
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		opErr       *net.OpError
+		netErr      net.Error
+	)
+	if code, ok := proxyErrorCode(err); ok {
+		return code
+	}
+	switch {
+	case errors.Is(err, errTooManyRedirects):
+		return "HTTP_TOO_MANY_REDIRECTS"
+	case errors.As(err, &dnsErr):
+		return "HTTP_DNS_FAILED"
+	case errors.As(err, &certErr), errors.As(err, &unknownCA), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return "HTTP_TLS_CERT_INVALID"
+	case errors.As(err, &recordErr):
+		return "HTTP_TLS_FAILED"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "HTTP_TIMEOUT"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "HTTP_CONNECT_FAILED"
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		return "HTTP_TLS_FAILED"
+	}
+	return "HTTP_FAILED"
+}
@@ -0,0 +1,240 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// ICMP echo message types.
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// icmpConn is an ICMP socket: either an unprivileged datagram socket
+// ("ping socket", where the kernel owns the echo identifier) or a raw one.
+type icmpConn struct {
+	net.PacketConn
+	datagram bool
+	ipv6     bool
+}
+
+func (c *icmpConn) addr(ip net.IP) net.Addr {
+	if c.datagram {
+		return &net.UDPAddr{IP: ip}
+	}
+	return &net.IPAddr{IP: ip}
+}
+
+// pingStats summarizes one round of echo requests.
+type pingStats struct {
+	sent     int
+	received int
+	rtts     []time.Duration
+}
+
+func (s *pingStats) lossPct() float64 {
+	if s.sent == 0 {
+		return 100
+	}
+	return float64(s.sent-s.received) * 100 / float64(s.sent)
+}
+
+func (s *pingStats) minAvgMax() (min, avg, max time.Duration) {
+	if len(s.rtts) == 0 {
+		return 0, 0, 0
+	}
+	var sum time.Duration
+	min = s.rtts[0]
+	for _, rtt := range s.rtts {
+		sum += rtt
+		if rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+	}
+	return min, sum / time.Duration(len(s.rtts)), max
+}
+
+// resolvePingTarget returns the address to ping, honoring the family.
+func resolvePingTarget(ctx context.Context, target, family string) (net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		if (family == FamilyIPv4 && ip.To4() == nil) || (family == FamilyIPv6 && ip.To4() != nil) {
+			return nil, fmt.Errorf("%s is not an %s address", target, family)
+		}
+		return ip, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network("ip", family), target)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		// Without a family, prefer IPv4 like the system ping does
+		if family != "" || ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
+
+// echo sends count echo requests to ip, interval apart, and collects the
+// replies until all have arrived or ctx expires.
+func echo(ctx context.Context, conn *icmpConn, ip net.IP, count int, interval time.Duration) (*pingStats, error) {
+	// A random token in the payload tells our replies apart from those to
+	// other pingers sharing a raw socket's view of all ICMP traffic.
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	id := uint16(os.Getpid())
+	seqBase := binary.BigEndian.Uint16(token)
+
+	reqType, replyType := byte(icmpEchoRequest), byte(icmpEchoReply)
+	if conn.ipv6 {
+		reqType, replyType = icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+
+	stats := &pingStats{}
+	sentAt := make([]time.Time, count)
+	answered := make([]bool, count)
+	nextSend := time.Now()
+	buf := make([]byte, 1500)
+
+	for stats.received < count {
+		now := time.Now()
+		if stats.sent < count && !now.Before(nextSend) {
+			seq := seqBase + uint16(stats.sent)
+			msg := marshalEcho(reqType, id, seq, token, !conn.ipv6)
+			sentAt[stats.sent] = now
+			if _, err := conn.WriteTo(msg, conn.addr(ip)); err != nil {
+				return stats, fmt.Errorf("send echo request: %w", err)
+			}
+			stats.sent++
+			nextSend = now.Add(interval)
+			continue
+		}
+
+		// Wait for replies until the next probe is due or time runs out
+		wait := deadline
+		if stats.sent < count && nextSend.Before(wait) {
+			wait = nextSend
+		}
+		if !now.Before(deadline) {
+			break
+		}
+		conn.SetReadDeadline(wait)
+
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return stats, fmt.Errorf("read echo reply: %w", err)
+		}
+
+		typ, replyID, seq, data, ok := parseEcho(buf[:n])
+		if !ok || typ != replyType || !bytes.Equal(data, token) {
+			continue
+		}
+		// Ping sockets rewrite the identifier; the kernel already
+		// delivers only our own replies to them.
+		if !conn.datagram && replyID != id {
+			continue
+		}
+		i := int(seq - seqBase)
+		if i < 0 || i >= stats.sent || answered[i] {
+			continue
+		}
+		answered[i] = true
+		stats.received++
+		stats.rtts = append(stats.rtts, time.Since(sentAt[i]))
+	}
+
+	return stats, nil
+}
+
+// marshalEcho builds an echo request. ICMPv6 checksums cover a pseudo
+// header and are always filled in by the kernel.
+func marshalEcho(typ byte, id, seq uint16, data []byte, checksum bool) []byte {
+	msg := make([]byte, 8+len(data))
+	msg[0] = typ
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	copy(msg[8:], data)
+	if checksum {
+		binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+	}
+	return msg
+}
+
+func parseEcho(b []byte) (typ byte, id, seq uint16, data []byte, ok bool) {
+	if len(b) < 8 || b[1] != 0 {
+		return 0, 0, 0, nil, false
+	}
+	return b[0], binary.BigEndian.Uint16(b[4:]), binary.BigEndian.Uint16(b[6:]), b[8:], true
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// errNoICMPSocket means neither a ping socket nor a raw socket is allowed.
+var errNoICMPSocket = errors.New("no ICMP socket available")
+
+// listenRawICMP opens a privileged raw ICMP socket bound to src.
+func listenRawICMP(ipv6 bool, src Source) (*icmpConn, error) {
+	netw, addr := "ip4:icmp", "0.0.0.0"
+	if ipv6 {
+		netw, addr = "ip6:ipv6-icmp", "::"
+	}
+	if src.IP != nil {
+		addr = src.IP.String()
+	}
+	conn, err := net.ListenPacket(netw, addr)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, errNoICMPSocket
+		}
+		return nil, err
+	}
+
+	if src.Iface != "" || src.Mark != 0 {
+		raw, err := conn.(*net.IPConn).SyscallConn()
+		if err == nil {
+			err = src.control(raw)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &icmpConn{PacketConn: conn, ipv6: ipv6}, nil
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package checks
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenICMP opens an unprivileged ICMP datagram socket if
+// net.ipv4.ping_group_range allows it, and a raw socket otherwise.
+func listenICMP(ipv6 bool, src Source) (*icmpConn, error) {
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	var sa syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if ipv6 {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		sa = &syscall.SockaddrInet6{}
+	}
+	if src.IP != nil {
+		if ip4 := src.IP.To4(); ip4 != nil {
+			sa4 := &syscall.SockaddrInet4{}
+			copy(sa4.Addr[:], ip4)
+			sa = sa4
+		} else {
+			sa6 := &syscall.SockaddrInet6{}
+			copy(sa6.Addr[:], src.IP.To16())
+			sa = sa6
+		}
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return listenRawICMP(ipv6, src)
+	}
+	if err := src.apply(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// FilePacketConn dups the descriptor, so the file is closed either way
+	f := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return listenRawICMP(ipv6, src)
+	}
+	return &icmpConn{PacketConn: conn, datagram: true, ipv6: ipv6}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package checks
+
+// listenICMP opens a raw ICMP socket; datagram ping sockets are only
+// used on Linux.
+func listenICMP(ipv6 bool, src Source) (*icmpConn, error) {
+	return listenRawICMP(ipv6, src)
+}
@@ -0,0 +1,316 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Link-state checks read local kernel state instead of probing a remote
+// host, so an unplugged cable or a dropped session fails at once rather
+// than after a timeout.
+
+// IfaceChecker checks that an interface is up, has carrier and has an
+// address.
+type IfaceChecker struct {
+	iface  string
+	family string
+}
+
+func (c *IfaceChecker) Type() string   { return "iface" }
+func (c *IfaceChecker) Target() string { return c.iface }
+
+func (c *IfaceChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.iface,
+	}
+	code, msg := linkState(c.iface, c.family, "IFACE")
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	result.OK = code == ""
+	result.ErrorCode = code
+	result.Message = msg
+	return result
+}
+
+// PPPoEChecker checks the PPPoE session of OpenWrt's pppoe-wan (or
+// another ppp interface): the interface only exists while the session
+// is established, and gets its address from the peer.
+type PPPoEChecker struct {
+	iface  string
+	family string
+}
+
+func (c *PPPoEChecker) Type() string   { return "pppoe" }
+func (c *PPPoEChecker) Target() string { return c.iface }
+
+func (c *PPPoEChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.iface,
+	}
+	code, msg := linkState(c.iface, c.family, "PPPOE")
+	if code == "PPPOE_NOT_FOUND" {
+		code, msg = "PPPOE_NO_SESSION", fmt.Sprintf("no PPPoE session: %s does not exist", c.iface)
+	}
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	result.OK = code == ""
+	result.ErrorCode = code
+	result.Message = msg
+	return result
+}
+
+// linkState inspects an interface and returns an error code with the
+// given prefix (empty if the link is usable) and a message.
+func linkState(name, family, prefix string) (string, string) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return prefix + "_NOT_FOUND", fmt.Sprintf("interface %s not found", name)
+	}
+	if ifi.Flags&net.FlagUp == 0 {
+		return prefix + "_DOWN", fmt.Sprintf("%s is administratively down", name)
+	}
+
+	// carrier can't be read while the link is down; operstate "unknown"
+	// is normal for ppp and tun devices
+	if carrier, err := readSysNet(name, "carrier"); err == nil && carrier == "0" {
+		return prefix + "_NO_CARRIER", fmt.Sprintf("%s has no carrier", name)
+	}
+	operstate, _ := readSysNet(name, "operstate")
+	switch operstate {
+	case "down", "lowerlayerdown", "notpresent":
+		return prefix + "_NO_CARRIER", fmt.Sprintf("%s operstate is %s", name, operstate)
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return prefix + "_NO_ADDRESS", fmt.Sprintf("read addresses of %s: %v", name, err)
+	}
+	var usable []string
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() {
+			continue
+		}
+		is4 := ipnet.IP.To4() != nil
+		if (family == FamilyIPv4 && !is4) || (family == FamilyIPv6 && is4) {
+			continue
+		}
+		usable = append(usable, ipnet.String())
+	}
+	if len(usable) == 0 {
+		return prefix + "_NO_ADDRESS", fmt.Sprintf("%s has no %saddress", name, familyWord(family))
+	}
+
+	msg := fmt.Sprintf("%s up", name)
+	if operstate != "" {
+		msg += ", operstate " + operstate
+	}
+	return "", msg + ", " + strings.Join(usable, ", ")
+}
+
+func familyWord(family string) string {
+	switch family {
+	case FamilyIPv4:
+		return "IPv4 "
+	case FamilyIPv6:
+		return "IPv6 "
+	}
+	return ""
+}
+
+func readSysNet(iface, attr string) (string, error) {
+	b, err := os.ReadFile("/sys/class/net/" + iface + "/" + attr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// RouteChecker checks that a default route exists, optionally via an
+// expected gateway or device.
+type RouteChecker struct {
+	gateway net.IP
+	device  string
+	family  string
+}
+
+// defaultRoute is a default route from the main routing table.
+type defaultRoute struct {
+	gateway net.IP // nil for a device route, e.g. over ppp
+	device  string
+	metric  int
+}
+
+func (c *RouteChecker) Type() string { return "route" }
+
+func (c *RouteChecker) Target() string {
+	target := "default"
+	if c.family == FamilyIPv6 {
+		target = "default6"
+	}
+	if c.gateway != nil {
+		target += " via " + c.gateway.String()
+	}
+	if c.device != "" {
+		target += " dev " + c.device
+	}
+	return target
+}
+
+func (c *RouteChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+
+	var routes []defaultRoute
+	var err error
+	if c.family == FamilyIPv6 {
+		routes, err = defaultRoutes6()
+	} else {
+		routes, err = defaultRoutes4()
+	}
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	if err != nil {
+		result.ErrorCode = "ROUTE_FAILED"
+		result.Message = fmt.Sprintf("read routing table: %v", err)
+		return result
+	}
+	result.ErrorCode, result.Message = c.evaluate(routes)
+	result.OK = result.ErrorCode == ""
+	return result
+}
+
+// evaluate checks the default routes, ordered by metric, against the
+// expected gateway and device. Only the lowest-metric route decides where
+// traffic goes; a backup default route via the expected gateway doesn't
+// count.
+func (c *RouteChecker) evaluate(routes []defaultRoute) (string, string) {
+	if len(routes) == 0 {
+		return "ROUTE_NO_DEFAULT", "no default route"
+	}
+	best := routes[0]
+	switch {
+	case c.gateway != nil && !c.gateway.Equal(best.gateway):
+		return "ROUTE_WRONG_GATEWAY", fmt.Sprintf("default route is %s", best)
+	case c.device != "" && c.device != best.device:
+		return "ROUTE_WRONG_DEVICE", fmt.Sprintf("default route is %s", best)
+	}
+	return "", "default " + best.String()
+}
+
+func (r defaultRoute) String() string {
+	s := "dev " + r.device
+	if r.gateway != nil {
+		s = "via " + r.gateway.String() + " " + s
+	}
+	return fmt.Sprintf("%s metric %d", s, r.metric)
+}
+
+// Route flags from linux/route.h.
+const (
+	rtfUp     = 0x0001
+	rtfReject = 0x0200
+)
+
+// defaultRoutes4 reads the active default routes from /proc/net/route,
+// ordered by metric.
+func defaultRoutes4() ([]defaultRoute, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRoutes4(f, binary.NativeEndian)
+}
+
+// parseRoutes4 parses /proc/net/route. The kernel prints addresses as
+// 32-bit values in host byte order, so order must be the host's.
+func parseRoutes4(r io.Reader, order binary.ByteOrder) ([]defaultRoute, error) {
+	var routes []defaultRoute
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		if flags&rtfUp == 0 || flags&rtfReject != 0 {
+			continue
+		}
+		route := defaultRoute{device: fields[0]}
+		route.metric, _ = strconv.Atoi(fields[6])
+		if gw, err := strconv.ParseUint(fields[2], 16, 32); err == nil && gw != 0 {
+			ip := make(net.IP, 4)
+			order.PutUint32(ip, uint32(gw))
+			route.gateway = ip
+		}
+		routes = insertByMetric(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// defaultRoutes6 reads the active default routes from
+// /proc/net/ipv6_route, ordered by metric.
+func defaultRoutes6() ([]defaultRoute, error) {
+	f, err := os.Open("/proc/net/ipv6_route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRoutes6(f)
+}
+
+// parseRoutes6 parses /proc/net/ipv6_route, which prints addresses byte
+// by byte in network order.
+func parseRoutes6(r io.Reader) ([]defaultRoute, error) {
+	var routes []defaultRoute
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// dst dst_len src src_len nexthop metric refcnt use flags iface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] != strings.Repeat("0", 32) || fields[1] != "00" {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[8], 16, 32)
+		if flags&rtfUp == 0 || flags&rtfReject != 0 || fields[9] == "lo" {
+			continue
+		}
+		route := defaultRoute{device: fields[9]}
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		route.metric = int(metric)
+		if gw, err := hex.DecodeString(fields[4]); err == nil && len(gw) == 16 && fields[4] != strings.Repeat("0", 32) {
+			route.gateway = net.IP(gw)
+		}
+		routes = insertByMetric(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+func insertByMetric(routes []defaultRoute, r defaultRoute) []defaultRoute {
+	i := len(routes)
+	for i > 0 && routes[i-1].metric > r.metric {
+		i--
+	}
+	routes = append(routes, defaultRoute{})
+	copy(routes[i+1:], routes[i:])
+	routes[i] = r
+	return routes
+}
@@ -0,0 +1,104 @@
+package checks
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// routeTable builds /proc/net/route text with the given gateway column
+// for the eth1 route, as printed on a host of either byte order.
+func routeTable(eth1Gateway string) string {
+	return "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth1\t00000000\t" + eth1Gateway + "\t0003\t0\t0\t20\t00000000\t0\t0\t0\n" +
+		"pppoe-wan\t00000000\t00000000\t0001\t0\t0\t10\t00000000\t0\t0\t0\n" +
+		"eth2\t00000000\t0101A8C0\t0203\t0\t0\t0\t00000000\t0\t0\t0\n" + // Reject route
+		"eth3\t00000000\t0102A8C0\t0002\t0\t0\t0\t00000000\t0\t0\t0\n" + // Not up
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" // Not a default route
+}
+
+func TestParseRoutes4(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		order binary.ByteOrder
+		want  []string
+	}{
+		{
+			name:  "little-endian host",
+			text:  routeTable("0100A8C0"),
+			order: binary.LittleEndian,
+			want:  []string{"dev pppoe-wan metric 10", "via 192.168.0.1 dev eth1 metric 20"},
+		},
+		{
+			name:  "big-endian host",
+			text:  routeTable("C0A80001"),
+			order: binary.BigEndian,
+			want:  []string{"dev pppoe-wan metric 10", "via 192.168.0.1 dev eth1 metric 20"},
+		},
+		{
+			name:  "no default route",
+			text:  "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n",
+			order: binary.LittleEndian,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := parseRoutes4(strings.NewReader(tt.text), tt.order)
+			if err != nil {
+				t.Fatalf("parseRoutes4: %v", err)
+			}
+			var got []string
+			for _, r := range routes {
+				got = append(got, r.String())
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("routes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoutes6(t *testing.T) {
+	text := "00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth1\n" +
+		"00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo\n" +
+		"fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0\n"
+	routes, err := parseRoutes6(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parseRoutes6: %v", err)
+	}
+	if len(routes) != 1 || routes[0].String() != "via fe80::1 dev eth1 metric 1024" {
+		t.Errorf("routes = %v, want [via fe80::1 dev eth1 metric 1024]", routes)
+	}
+}
+
+func TestRouteEvaluate(t *testing.T) {
+	routes, err := parseRoutes4(strings.NewReader(routeTable("0100A8C0")), binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("parseRoutes4: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		checker  RouteChecker
+		routes   []defaultRoute
+		wantCode string
+	}{
+		{"any default route", RouteChecker{}, routes, ""},
+		{"lowest-metric device", RouteChecker{device: "pppoe-wan"}, routes, ""},
+		{"backup route's gateway doesn't count", RouteChecker{gateway: net.ParseIP("192.168.0.1")}, routes, "ROUTE_WRONG_GATEWAY"},
+		{"backup route's device doesn't count", RouteChecker{device: "eth1"}, routes, "ROUTE_WRONG_DEVICE"},
+		{"gateway of the preferred route", RouteChecker{gateway: net.ParseIP("192.168.0.1")}, routes[1:], ""},
+		{"no default route", RouteChecker{}, nil, "ROUTE_NO_DEFAULT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, msg := tt.checker.evaluate(tt.routes)
+			if code != tt.wantCode {
+				t.Errorf("evaluate = %q (%s), want %q", code, msg, tt.wantCode)
+			}
+		})
+	}
+}
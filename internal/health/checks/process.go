@@ -0,0 +1,223 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessChecker checks that a local daemon, typically the proxy, is
+// running and optionally listening. Pinging domestic IPs keeps passing
+// after the proxy crashes; this check makes that a failover.
+type ProcessChecker struct {
+	name       string
+	pidFile    string
+	cmdline    *regexp.Regexp
+	listenPort int
+}
+
+func newProcessChecker(target string, params Params) (*ProcessChecker, error) {
+	c := &ProcessChecker{
+		name:       target,
+		pidFile:    params.String("pidfile"),
+		listenPort: params.Int("listen_port"),
+	}
+	cmdline := params.String("cmdline")
+	if c.name == "" && c.pidFile == "" && cmdline == "" {
+		return nil, fmt.Errorf("process check requires target, pidfile or cmdline")
+	}
+	if cmdline != "" {
+		re, err := regexp.Compile(cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cmdline regex: %w", err)
+		}
+		c.cmdline = re
+	}
+	if c.listenPort < 0 || c.listenPort > 65535 {
+		return nil, fmt.Errorf("invalid listen_port %d", c.listenPort)
+	}
+	return c, nil
+}
+
+func (c *ProcessChecker) Type() string { return "process" }
+
+func (c *ProcessChecker) Target() string {
+	switch {
+	case c.name != "":
+		return c.name
+	case c.pidFile != "":
+		return c.pidFile
+	}
+	return c.cmdline.String()
+}
+
+func (c *ProcessChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+	code, msg := c.check()
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	result.OK = code == ""
+	result.ErrorCode = code
+	result.Message = msg
+	return result
+}
+
+func (c *ProcessChecker) check() (string, string) {
+	var candidates []int
+	if c.pidFile != "" {
+		b, err := os.ReadFile(c.pidFile)
+		if err != nil {
+			return "PROCESS_NOT_RUNNING", fmt.Sprintf("read pidfile: %v", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil || pid <= 0 {
+			return "PROCESS_NOT_RUNNING", fmt.Sprintf("invalid pid in %s", c.pidFile)
+		}
+		candidates = []int{pid}
+	} else {
+		entries, err := os.ReadDir("/proc")
+		if err != nil {
+			return "PROCESS_FAILED", fmt.Sprintf("list processes: %v", err)
+		}
+		for _, e := range entries {
+			// Skip ourselves, whose cmdline may contain the pattern
+			if pid, err := strconv.Atoi(e.Name()); err == nil && pid != os.Getpid() {
+				candidates = append(candidates, pid)
+			}
+		}
+	}
+
+	var pids []int
+	for _, pid := range candidates {
+		if c.matches(pid) {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) == 0 {
+		return "PROCESS_NOT_RUNNING", fmt.Sprintf("no running process matches %s", c.describe())
+	}
+
+	msg := fmt.Sprintf("running (pid %s)", joinPIDs(pids))
+	if c.listenPort == 0 {
+		return "", msg
+	}
+	listening, err := tcpListening(c.listenPort)
+	if err != nil {
+		return "PROCESS_FAILED", fmt.Sprintf("read sockets: %v", err)
+	}
+	if !listening {
+		return "PROCESS_NOT_LISTENING", fmt.Sprintf("%s, but nothing listens on TCP port %d", msg, c.listenPort)
+	}
+	return "", fmt.Sprintf("%s, listening on TCP port %d", msg, c.listenPort)
+}
+
+// matches reports whether pid is a live (not zombie) process matching
+// the name and cmdline.
+func (c *ProcessChecker) matches(pid int) bool {
+	dir := "/proc/" + strconv.Itoa(pid)
+	stat, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return false
+	}
+	// pid (comm) state ...; comm may itself contain spaces and parens
+	if i := bytes.LastIndexByte(stat, ')'); i < 0 || i+2 >= len(stat) || stat[i+2] == 'Z' {
+		return false
+	}
+
+	var args []string
+	if c.name != "" || c.cmdline != nil {
+		raw, _ := os.ReadFile(dir + "/cmdline")
+		args = strings.Split(strings.TrimRight(string(raw), "\x00"), "\x00")
+	}
+	if c.name != "" {
+		comm, _ := os.ReadFile(dir + "/comm")
+		// comm is truncated to 15 bytes; argv[0] has the full name
+		name := c.name
+		if len(name) > 15 {
+			name = name[:15]
+		}
+		if strings.TrimSpace(string(comm)) != name && filepath.Base(args[0]) != c.name {
+			return false
+		}
+	}
+	if c.cmdline != nil && !c.cmdline.MatchString(strings.Join(args, " ")) {
+		return false
+	}
+	return true
+}
+
+func (c *ProcessChecker) describe() string {
+	var parts []string
+	if c.name != "" {
+		parts = append(parts, "name "+c.name)
+	}
+	if c.pidFile != "" {
+		parts = append(parts, "pidfile "+c.pidFile)
+	}
+	if c.cmdline != nil {
+		parts = append(parts, fmt.Sprintf("cmdline /%s/", c.cmdline))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinPIDs(pids []int) string {
+	const maxShown = 5
+	var s []string
+	for i, pid := range pids {
+		if i == maxShown {
+			s = append(s, fmt.Sprintf("+%d more", len(pids)-maxShown))
+			break
+		}
+		s = append(s, strconv.Itoa(pid))
+	}
+	return strings.Join(s, ", ")
+}
+
+// tcpListening reports whether any socket in /proc/net/tcp or tcp6 is
+// listening on port.
+func tcpListening(port int) (bool, error) {
+	want := fmt.Sprintf(":%04X", port)
+	var firstErr error
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		found, err := scanListening(path, want)
+		if found {
+			return true, nil
+		}
+		// tcp6 is missing without IPv6
+		if err != nil && firstErr == nil && !os.IsNotExist(err) {
+			firstErr = err
+		}
+	}
+	return false, firstErr
+}
+
+func scanListening(path, port string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	const stateListen = "0A"
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 4 && fields[3] == stateListen && strings.HasSuffix(fields[1], port) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
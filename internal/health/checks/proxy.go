@@ -0,0 +1,243 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ProxyError is a failure of the proxy itself rather than of the target,
+// classified with its own ErrorCode.
+type ProxyError struct {
+	Code string // PROXY_CONNECT_FAILED, PROXY_AUTH_FAILED or PROXY_REJECTED
+	Err  error
+}
+
+func (e *ProxyError) Error() string { return e.Err.Error() }
+func (e *ProxyError) Unwrap() error { return e.Err }
+
+// parseProxy validates a proxy URL: http://[user:pass@]host:port or
+// socks5://[user:pass@]host:port.
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http or socks5", raw)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("invalid proxy %q: port is required", raw)
+	}
+	return u, nil
+}
+
+// proxyName returns the proxy address without credentials.
+func proxyName(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// dialProxy opens a tunnel to address through the proxy. The connection
+// to the proxy itself is made from the check's source binding.
+func dialProxy(ctx context.Context, proxy *url.URL, src Source, network, address string, timeout time.Duration) (net.Conn, error) {
+	conn, err := src.Dial(ctx, network, proxy.Host, timeout)
+	if err != nil {
+		return nil, &ProxyError{Code: "PROXY_CONNECT_FAILED", Err: fmt.Errorf("connect to proxy %s: %w", proxyName(proxy), err)}
+	}
+
+	// Bound the handshake by the check deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if proxy.Scheme == "http" {
+		err = httpConnect(conn, proxy, address)
+	} else {
+		err = socks5Connect(conn, proxy, address)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// httpConnect establishes a tunnel with the HTTP CONNECT method.
+func httpConnect(conn net.Conn, proxy *url.URL, address string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		return &ProxyError{Code: "PROXY_CONNECT_FAILED", Err: fmt.Errorf("send CONNECT: %w", err)}
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return &ProxyError{Code: "PROXY_CONNECT_FAILED", Err: fmt.Errorf("read CONNECT response: %w", err)}
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		return &ProxyError{Code: "PROXY_AUTH_FAILED", Err: fmt.Errorf("proxy %s: %s", proxyName(proxy), resp.Status)}
+	case resp.StatusCode != http.StatusOK:
+		return &ProxyError{Code: "PROXY_REJECTED", Err: fmt.Errorf("proxy %s refused CONNECT %s: %s", proxyName(proxy), address, resp.Status)}
+	}
+	return nil
+}
+
+// SOCKS5 reply codes (RFC 1928 section 6).
+var socks5Replies = map[byte]string{
+	1: "general failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// socks5Connect performs a SOCKS5 CONNECT (RFC 1928) with optional
+// username/password authentication (RFC 1929). Host names are passed to
+// the proxy unresolved so it can apply its own DNS and routing rules.
+func socks5Connect(conn net.Conn, proxy *url.URL, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port in %q", address)
+	}
+
+	fail := func(err error) error {
+		return &ProxyError{Code: "PROXY_CONNECT_FAILED", Err: fmt.Errorf("proxy %s: %w", proxyName(proxy), err)}
+	}
+
+	// Greeting: offer no-auth, plus user/pass if credentials are set
+	methods := []byte{0x00}
+	if proxy.User != nil {
+		methods = []byte{0x00, 0x02}
+	}
+	if _, err := conn.Write(append([]byte{5, byte(len(methods))}, methods...)); err != nil {
+		return fail(err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fail(err)
+	}
+	if reply[0] != 5 {
+		return fail(fmt.Errorf("not a SOCKS5 server"))
+	}
+
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if proxy.User == nil {
+			return &ProxyError{Code: "PROXY_AUTH_FAILED", Err: fmt.Errorf("proxy %s requires authentication", proxyName(proxy))}
+		}
+		user := proxy.User.Username()
+		pass, _ := proxy.User.Password()
+		msg := []byte{1, byte(len(user))}
+		msg = append(msg, user...)
+		msg = append(msg, byte(len(pass)))
+		msg = append(msg, pass...)
+		if _, err := conn.Write(msg); err != nil {
+			return fail(err)
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fail(err)
+		}
+		if reply[1] != 0 {
+			return &ProxyError{Code: "PROXY_AUTH_FAILED", Err: fmt.Errorf("proxy %s: authentication rejected", proxyName(proxy))}
+		}
+	default:
+		return &ProxyError{Code: "PROXY_AUTH_FAILED", Err: fmt.Errorf("proxy %s: no acceptable authentication method", proxyName(proxy))}
+	}
+
+	// CONNECT request
+	req := []byte{5, 1, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, 1)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, 4)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fail(fmt.Errorf("host name too long"))
+		}
+		req = append(req, 3, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fail(err)
+	}
+
+	// Reply: VER REP RSV ATYP BND.ADDR BND.PORT
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fail(err)
+	}
+	if head[1] != 0 {
+		reason, ok := socks5Replies[head[1]]
+		if !ok {
+			reason = fmt.Sprintf("reply code %d", head[1])
+		}
+		return &ProxyError{Code: "PROXY_REJECTED", Err: fmt.Errorf("proxy %s refused CONNECT %s: %s", proxyName(proxy), address, reason)}
+	}
+
+	var skip int
+	switch head[3] {
+	case 1:
+		skip = net.IPv4len
+	case 4:
+		skip = net.IPv6len
+	case 3:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return fail(err)
+		}
+		skip = int(l[0])
+	default:
+		return fail(fmt.Errorf("invalid bound address type %d", head[3]))
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// proxyErrorCode returns the code of a proxy failure, if err is one.
+func proxyErrorCode(err error) (string, bool) {
+	var pe *ProxyError
+	if errors.As(err, &pe) {
+		return pe.Code, true
+	}
+	return "", false
+}
@@ -0,0 +1,288 @@
+package checks
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// ParamKind is the type of a checker parameter.
+type ParamKind string
+
+const (
+	KindString  ParamKind = "string"
+	KindInt     ParamKind = "int"
+	KindFloat   ParamKind = "float"
+	KindBool    ParamKind = "bool"
+	KindStrings ParamKind = "[]string"
+	KindInts    ParamKind = "[]int"
+	KindMap     ParamKind = "map"
+)
+
+// Param describes one parameter of a checker type, set as a key of the
+// check. Parameters named after a CheckConfig field (by YAML key) are read
+// from it; any other parameter from the remaining keys in Extra.
+type Param struct {
+	Name     string
+	Kind     ParamKind
+	Required bool
+	Doc      string
+}
+
+// Params holds a check's parameters, converted to the Go type of their
+// kind: string, int, float64, bool, []string, []int or map[string]string.
+// Parameters that are not set are absent.
+type Params map[string]any
+
+func (p Params) Has(name string) bool { _, ok := p[name]; return ok }
+
+func (p Params) String(name string) string    { v, _ := p[name].(string); return v }
+func (p Params) Int(name string) int          { v, _ := p[name].(int); return v }
+func (p Params) Float(name string) float64    { v, _ := p[name].(float64); return v }
+func (p Params) Bool(name string) bool        { v, _ := p[name].(bool); return v }
+func (p Params) Strings(name string) []string { v, _ := p[name].([]string); return v }
+func (p Params) Ints(name string) []int       { v, _ := p[name].([]int); return v }
+func (p Params) Map(name string) map[string]string {
+	v, _ := p[name].(map[string]string)
+	return v
+}
+
+// Options is what a factory gets besides the check's config: the common
+// settings resolved and validated, and the typed parameters.
+type Options struct {
+	Timeout time.Duration
+	Family  string   // FamilyIPv4, FamilyIPv6 or empty
+	Source  Source   // Egress binding from source_iface/source_ip/mark
+	Proxy   *url.URL // Set only for types declaring a "proxy" parameter
+	Params  Params
+}
+
+// Factory builds checkers of one type.
+type Factory struct {
+	Doc    string
+	Params []Param
+	New    func(cfg config.CheckConfig, opts Options) (Checker, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a checker type available to NewChecker. It is meant to
+// be called from init functions and panics on a duplicate type or a
+// parameter whose kind doesn't match its CheckConfig field.
+func Register(typ string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[typ]; dup {
+		panic("checks: Register called twice for type " + typ)
+	}
+	if factory.New == nil {
+		panic("checks: Register with nil New for type " + typ)
+	}
+	for _, p := range factory.Params {
+		if f, ok := configFields[p.Name]; ok && fieldKind(f.Type) != p.Kind {
+			panic(fmt.Sprintf("checks: %s parameter %s is %s, but the config field is %s", typ, p.Name, p.Kind, fieldKind(f.Type)))
+		}
+	}
+	registry[typ] = factory
+}
+
+// Types returns the registered checker types, sorted.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for typ := range registry {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Lookup returns the factory of a checker type.
+func Lookup(typ string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[typ]
+	return f, ok
+}
+
+// HasParam reports whether the type declares a parameter.
+func (f Factory) HasParam(name string) bool {
+	for _, p := range f.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CommonParams are accepted by every checker type and handled by
+// NewChecker and the policy rather than by the factories.
+var CommonParams = []string{"timeout", "family", "weight", "critical", "interval", "jitter", "max_age", "source_iface", "source_ip", "mark"}
+
+// configFields maps CheckConfig YAML keys to their fields.
+var configFields = func() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	t := reflect.TypeOf(config.CheckConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i)
+		}
+	}
+	return fields
+}()
+
+func fieldKind(t reflect.Type) ParamKind {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return KindString
+	case reflect.Int:
+		return KindInt
+	case reflect.Float64:
+		return KindFloat
+	case reflect.Bool:
+		return KindBool
+	case reflect.Slice:
+		switch t.Elem().Kind() {
+		case reflect.String:
+			return KindStrings
+		case reflect.Int:
+			return KindInts
+		}
+	case reflect.Map:
+		return KindMap
+	}
+	return ParamKind(t.String())
+}
+
+// params collects the declared parameters of a check from its config
+// fields and other keys, checking kinds and required parameters.
+func (f Factory) params(typ string, cfg config.CheckConfig) (Params, error) {
+	params := make(Params)
+	v := reflect.ValueOf(cfg)
+
+	declared := make(map[string]bool, len(f.Params))
+	for _, p := range f.Params {
+		declared[p.Name] = true
+
+		if field, ok := configFields[p.Name]; ok {
+			fv := v.FieldByIndex(field.Index)
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			} else if fv.IsZero() {
+				continue
+			}
+			params[p.Name] = fv.Interface()
+			continue
+		}
+
+		if raw, ok := cfg.Extra[p.Name]; ok {
+			val, err := convertParam(p.Kind, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.Name, err)
+			}
+			params[p.Name] = val
+		}
+	}
+
+	for _, p := range f.Params {
+		if p.Required && !params.Has(p.Name) {
+			return nil, fmt.Errorf("%s check requires %s", typ, p.Name)
+		}
+	}
+	for name := range cfg.Extra {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter %q for %s check", name, typ)
+		}
+	}
+	return params, nil
+}
+
+// convertParam converts a value decoded from YAML to the Go type of kind.
+func convertParam(kind ParamKind, raw any) (any, error) {
+	switch kind {
+	case KindString:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case KindInt:
+		switch n := raw.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == float64(int(n)) {
+				return int(n), nil
+			}
+		}
+	case KindFloat:
+		switch n := raw.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case KindBool:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+	case KindStrings, KindInts:
+		list, ok := raw.([]any)
+		if !ok {
+			break
+		}
+		elem := KindString
+		if kind == KindInts {
+			elem = KindInt
+		}
+		var strs []string
+		var ints []int
+		for _, item := range list {
+			val, err := convertParam(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			if kind == KindInts {
+				ints = append(ints, val.(int))
+			} else {
+				strs = append(strs, val.(string))
+			}
+		}
+		if kind == KindInts {
+			return ints, nil
+		}
+		return strs, nil
+	case KindMap:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			break
+		}
+		out := make(map[string]string, len(m))
+		for k, item := range m {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("value of %q must be a string", k)
+			}
+			out[k] = s
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected %s, got %T", kind, raw)
+}
@@ -0,0 +1,154 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceChecker fails when the router itself is under pressure: a
+// nearly full conntrack table drops new connections, low memory gets the
+// proxy OOM-killed, and a high load delays forwarding. An overloaded
+// router can then give up the VIP before clients notice.
+type ResourceChecker struct {
+	maxConntrackPct    float64
+	minMemAvailablePct float64
+	maxLoad            float64 // Per CPU, 0 = not checked
+}
+
+func (c *ResourceChecker) Type() string   { return "resource" }
+func (c *ResourceChecker) Target() string { return "local" }
+
+func (c *ResourceChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+	code, msg := c.check()
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	result.OK = code == ""
+	result.ErrorCode = code
+	result.Message = msg
+	return result
+}
+
+// check reads every resource and reports the first threshold crossed;
+// the message always lists all readings.
+func (c *ResourceChecker) check() (string, string) {
+	var parts []string
+	var code, reason string
+	fail := func(failCode, failReason string) {
+		if code == "" {
+			code, reason = failCode, failReason
+		}
+	}
+
+	// Without the nf_conntrack module there is no table to fill up
+	if count, limit, err := readConntrack(); err == nil && limit > 0 {
+		pct := float64(count) * 100 / float64(limit)
+		parts = append(parts, fmt.Sprintf("conntrack %d/%d (%.1f%%)", count, limit, pct))
+		if pct >= c.maxConntrackPct {
+			fail("RESOURCE_CONNTRACK_FULL", fmt.Sprintf("conntrack table %.1f%% full (max %.0f%%)", pct, c.maxConntrackPct))
+		}
+	}
+
+	total, available, err := readMeminfo()
+	if err != nil {
+		return "RESOURCE_FAILED", fmt.Sprintf("read /proc/meminfo: %v", err)
+	}
+	if total > 0 {
+		pct := float64(available) * 100 / float64(total)
+		parts = append(parts, fmt.Sprintf("memory %d/%d MiB available (%.1f%%)", available>>10, total>>10, pct))
+		if pct < c.minMemAvailablePct {
+			fail("RESOURCE_LOW_MEMORY", fmt.Sprintf("only %.1f%% memory available (min %.0f%%)", pct, c.minMemAvailablePct))
+		}
+	}
+
+	load, err := readLoadavg()
+	if err != nil {
+		return "RESOURCE_FAILED", fmt.Sprintf("read /proc/loadavg: %v", err)
+	}
+	cpus := runtime.NumCPU()
+	parts = append(parts, fmt.Sprintf("load %.2f on %d CPUs", load, cpus))
+	if c.maxLoad > 0 && load/float64(cpus) > c.maxLoad {
+		fail("RESOURCE_HIGH_LOAD", fmt.Sprintf("load %.2f per CPU (max %.2f)", load/float64(cpus), c.maxLoad))
+	}
+
+	msg := strings.Join(parts, ", ")
+	if code != "" {
+		msg = reason + ": " + msg
+	}
+	return code, msg
+}
+
+func readConntrack() (count, limit int, err error) {
+	if count, err = readProcInt("/proc/sys/net/netfilter/nf_conntrack_count"); err != nil {
+		return 0, 0, err
+	}
+	if limit, err = readProcInt("/proc/sys/net/netfilter/nf_conntrack_max"); err != nil {
+		return 0, 0, err
+	}
+	return count, limit, nil
+}
+
+func readProcInt(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// readMeminfo returns MemTotal and MemAvailable in KiB. Kernels before
+// 3.14 have no MemAvailable; MemFree + Buffers + Cached approximates it.
+func readMeminfo() (total, available int64, err error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// MemTotal:        1012340 kB
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err == nil {
+			fields[key] = kb
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	total = fields["MemTotal"]
+	available, ok := fields["MemAvailable"]
+	if !ok {
+		available = fields["MemFree"] + fields["Buffers"] + fields["Cached"]
+	}
+	return total, available, nil
+}
+
+// readLoadavg returns the 1-minute load average.
+func readLoadavg() (float64, error) {
+	b, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty /proc/loadavg")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
@@ -0,0 +1,155 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+	"github.com/zczy-k/FloatingGateway/internal/platform/exec"
+)
+
+// ScriptChecker runs an external command for checks the built-ins can't
+// express. Exit code 0 passes; stdout may carry a JSON object with
+// latency_ms, message and error_code.
+type ScriptChecker struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+// scriptOutput is the optional JSON a script prints on stdout.
+type scriptOutput struct {
+	LatencyMs *int64 `json:"latency_ms"`
+	Message   string `json:"message"`
+	ErrorCode string `json:"error_code"`
+}
+
+func newScriptChecker(cfg config.CheckConfig, timeout time.Duration) (*ScriptChecker, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("script check requires command")
+	}
+	if !filepath.IsAbs(cfg.Command) {
+		return nil, fmt.Errorf("script command must be an absolute path, got %q", cfg.Command)
+	}
+	return &ScriptChecker{command: cfg.Command, args: cfg.Args, timeout: timeout}, nil
+}
+
+func (c *ScriptChecker) Type() string { return "script" }
+
+func (c *ScriptChecker) Target() string {
+	return strings.TrimSpace(c.command + " " + strings.Join(c.args, " "))
+}
+
+func (c *ScriptChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+
+	// Checked on every run, as the file can change after startup
+	if err := scriptSecure(c.command); err != nil {
+		result.ErrorCode = "SCRIPT_INSECURE"
+		if errors.Is(err, fs.ErrNotExist) {
+			result.ErrorCode = "SCRIPT_NOT_FOUND"
+		}
+		result.Message = err.Error()
+		return result
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmdResult := exec.Run(timeoutCtx, c.command, c.args...)
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+
+	var out scriptOutput
+	stdout := strings.TrimSpace(cmdResult.Stdout)
+	if strings.HasPrefix(stdout, "{") && json.Unmarshal([]byte(stdout), &out) == nil {
+		if out.LatencyMs != nil {
+			result.LatencyMs = *out.LatencyMs
+		}
+	} else {
+		out = scriptOutput{Message: firstLine(stdout)}
+	}
+
+	switch {
+	case errors.Is(timeoutCtx.Err(), context.DeadlineExceeded):
+		result.ErrorCode = "SCRIPT_TIMEOUT"
+		result.Message = fmt.Sprintf("script timed out after %v", c.timeout)
+	case cmdResult.ExitCode == -1:
+		result.ErrorCode = "SCRIPT_FAILED"
+		result.Message = fmt.Sprintf("run script: %v", cmdResult.Err)
+	case cmdResult.ExitCode != 0:
+		result.ErrorCode = out.ErrorCode
+		if result.ErrorCode == "" {
+			result.ErrorCode = fmt.Sprintf("SCRIPT_EXIT_%d", cmdResult.ExitCode)
+		}
+		result.Message = out.Message
+		if result.Message == "" {
+			result.Message = firstLine(strings.TrimSpace(cmdResult.Stderr))
+		}
+		if result.Message == "" {
+			result.Message = fmt.Sprintf("script exited with code %d", cmdResult.ExitCode)
+		}
+	default:
+		result.OK = true
+		result.Message = out.Message
+		if result.Message == "" {
+			result.Message = "script exited with code 0"
+		}
+	}
+	return result
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return truncate(strings.TrimSpace(line), 200)
+}
+
+// scriptSecure refuses scripts keepalived's enable_script_security would
+// refuse: the file and every directory above it must be owned by root
+// and not writable by group or others.
+func scriptSecure(path string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", path, err)
+	}
+
+	for p := resolved; ; p = filepath.Dir(p) {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("script %s: %w", path, err)
+		}
+		uid, ok := fileOwner(fi)
+		if !ok {
+			return fmt.Errorf("script %s: cannot determine the owner of %s", path, p)
+		}
+		if uid != 0 {
+			return fmt.Errorf("script %s: %s is not owned by root", path, p)
+		}
+		if fi.Mode().Perm()&0o022 != 0 {
+			return fmt.Errorf("script %s: %s is writable by group or others", path, p)
+		}
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+
+	fi, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", path, err)
+	}
+	if fi.IsDir() || fi.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("script %s is not executable", path)
+	}
+	return nil
+}
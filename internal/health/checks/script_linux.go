@@ -0,0 +1,17 @@
+//go:build linux
+
+package checks
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the owning uid of a file.
+func fileOwner(fi os.FileInfo) (uint32, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return st.Uid, true
+}
@@ -0,0 +1,82 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// Source pins a check's traffic to this router's own egress path instead
+// of whatever the routing table picks, which on a BACKUP node may lead
+// through the VIP holder.
+type Source struct {
+	Iface string // Bound with SO_BINDTODEVICE
+	IP    net.IP // Local address to send from
+	Mark  int    // SO_MARK routing mark for policy routing
+}
+
+// newSource builds the source binding of a check.
+func newSource(cfg config.CheckConfig) (Source, error) {
+	src := Source{Iface: cfg.SourceIface, Mark: cfg.Mark}
+	if cfg.SourceIP != "" {
+		src.IP = net.ParseIP(cfg.SourceIP)
+		if src.IP == nil {
+			return src, fmt.Errorf("invalid source_ip %q", cfg.SourceIP)
+		}
+		if (cfg.Family == FamilyIPv4 && src.IP.To4() == nil) || (cfg.Family == FamilyIPv6 && src.IP.To4() != nil) {
+			return src, fmt.Errorf("source_ip %s does not match family %s", cfg.SourceIP, cfg.Family)
+		}
+	}
+	return src, nil
+}
+
+// String describes the binding for error messages.
+func (s Source) String() string {
+	var parts []string
+	if s.Iface != "" {
+		parts = append(parts, "dev "+s.Iface)
+	}
+	if s.IP != nil {
+		parts = append(parts, "src "+s.IP.String())
+	}
+	if s.Mark != 0 {
+		parts = append(parts, fmt.Sprintf("mark %#x", s.Mark))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Dial connects from the bound source. The network must be a tcp or udp
+// variant.
+func (s Source) Dial(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	if s.IP != nil {
+		switch network {
+		case "udp", "udp4", "udp6":
+			d.LocalAddr = &net.UDPAddr{IP: s.IP}
+		default:
+			d.LocalAddr = &net.TCPAddr{IP: s.IP}
+		}
+	}
+	if s.Iface != "" || s.Mark != 0 {
+		d.Control = func(_, _ string, c syscall.RawConn) error {
+			return s.control(c)
+		}
+	}
+	return d.DialContext(ctx, network, address)
+}
+
+// control applies the interface and mark to a socket.
+func (s Source) control(c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = s.apply(int(fd))
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,24 @@
+//go:build linux
+
+package checks
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// apply sets SO_BINDTODEVICE and SO_MARK on a socket. Both need
+// CAP_NET_RAW or CAP_NET_ADMIN, which the agent has when run as root.
+func (s Source) apply(fd int) error {
+	if s.Iface != "" {
+		if err := syscall.BindToDevice(fd, s.Iface); err != nil {
+			return fmt.Errorf("bind to %s: %w", s.Iface, err)
+		}
+	}
+	if s.Mark != 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_MARK, s.Mark); err != nil {
+			return fmt.Errorf("set mark %#x: %w", s.Mark, err)
+		}
+	}
+	return nil
+}
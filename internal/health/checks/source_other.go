@@ -0,0 +1,14 @@
+//go:build !linux
+
+package checks
+
+import "fmt"
+
+// apply fails for interface and mark bindings, which are Linux-only;
+// source_ip works everywhere through the dialer's local address.
+func (s Source) apply(fd int) error {
+	if s.Iface != "" || s.Mark != 0 {
+		return fmt.Errorf("source_iface and mark are only supported on Linux")
+	}
+	return nil
+}
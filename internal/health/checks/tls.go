@@ -0,0 +1,126 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// TLSChecker completes a TLS handshake and reports the negotiated
+// protocol and the certificate expiry.
+type TLSChecker struct {
+	target    string
+	port      int
+	timeout   time.Duration
+	family    string
+	source    Source
+	tlsConfig *tls.Config
+}
+
+func newTLSChecker(cfg config.CheckConfig, timeout time.Duration, family string, source Source) (*TLSChecker, error) {
+	c := &TLSChecker{
+		target:  cfg.Target,
+		port:    cfg.Port,
+		timeout: timeout,
+		family:  family,
+		source:  source,
+	}
+	if c.port == 0 {
+		c.port = 443
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	// SNI defaults to the target; an IP target sends none and is
+	// verified against the certificate's IP SANs
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = cfg.Target
+	}
+	// Offer HTTP over ALPN so the result shows whether h2 is served. Only
+	// on 443: a server without a common protocol aborts the handshake.
+	if c.port == 443 && tlsConfig.NextProtos == nil {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	c.tlsConfig = tlsConfig
+
+	return c, nil
+}
+
+func (c *TLSChecker) Type() string   { return "tls" }
+func (c *TLSChecker) Target() string { return net.JoinHostPort(c.target, strconv.Itoa(c.port)) }
+
+func (c *TLSChecker) Check(ctx context.Context) *Result {
+	start := time.Now()
+	result := &Result{
+		Type:   c.Type(),
+		Target: c.Target(),
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.source.Dial(timeoutCtx, network("tcp", c.family), c.Target(), c.timeout)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.LatencyMs = result.Duration.Milliseconds()
+		result.ErrorCode = "TLS_CONNECT_FAILED"
+		result.Message = fmt.Sprintf("TCP connect failed: %v", err)
+		return result
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, c.tlsConfig)
+	err = tlsConn.HandshakeContext(timeoutCtx)
+	result.Duration = time.Since(start)
+	result.LatencyMs = result.Duration.Milliseconds()
+	if err != nil {
+		result.ErrorCode = tlsErrorCode(err)
+		result.Message = fmt.Sprintf("TLS handshake failed: %v", err)
+		return result
+	}
+
+	state := tlsConn.ConnectionState()
+	result.OK = true
+	result.Message = tls.VersionName(state.Version)
+	if state.NegotiatedProtocol != "" {
+		result.Message += " " + state.NegotiatedProtocol
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		days := int(time.Until(leaf.NotAfter).Hours() / 24)
+		result.Message += fmt.Sprintf(", certificate %q expires %s (%d days)",
+			leaf.Subject.CommonName, leaf.NotAfter.UTC().Format("2006-01-02"), days)
+	}
+	return result
+}
+
+// tlsErrorCode classifies a failed handshake.
+func tlsErrorCode(err error) string {
+	var (
+		invalidErr  x509.CertificateInvalidError
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		netErr      net.Error
+	)
+	switch {
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return "TLS_CERT_EXPIRED"
+	case errors.As(err, &invalidErr), errors.As(err, &unknownCA), errors.As(err, &hostnameErr):
+		return "TLS_CERT_INVALID"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "TLS_TIMEOUT"
+	}
+	return "TLS_HANDSHAKE_FAILED"
+}
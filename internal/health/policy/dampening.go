@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"math"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// dampener implements BGP-style flap dampening. Each state change adds a
+// penalty that decays exponentially; once it crosses the suppress
+// threshold, state changes are held until it decays below reuse.
+type dampener struct {
+	cfg        config.DampeningConfig
+	penalty    float64
+	updated    time.Time // When penalty was last decayed
+	suppressed bool
+}
+
+// decay brings the penalty up to now.
+func (d *dampener) decay(now time.Time) {
+	if !d.updated.IsZero() && now.After(d.updated) {
+		halfLives := float64(now.Sub(d.updated)) / float64(d.cfg.HalfLife())
+		d.penalty *= math.Exp2(-halfLives)
+	}
+	d.updated = now
+}
+
+// flap records a state change.
+func (d *dampener) flap(now time.Time) {
+	d.decay(now)
+	d.penalty = math.Min(d.penalty+d.cfg.GetPenalty(), d.cfg.MaxPenalty())
+	if d.penalty >= d.cfg.Suppress {
+		d.suppressed = true
+	}
+}
+
+// suppressing reports whether state changes are currently held, lifting
+// the suppression once the penalty has decayed below reuse.
+func (d *dampener) suppressing(now time.Time) bool {
+	d.decay(now)
+	if d.suppressed && d.penalty < d.cfg.GetReuse() {
+		d.suppressed = false
+	}
+	return d.suppressed
+}
+
+// reuseIn returns how long until a suppressed state is released.
+func (d *dampener) reuseIn() time.Duration {
+	if !d.suppressed || d.penalty <= d.cfg.GetReuse() {
+		return 0
+	}
+	halfLives := math.Log2(d.penalty / d.cfg.GetReuse())
+	return time.Duration(halfLives * float64(d.cfg.HalfLife()))
+}
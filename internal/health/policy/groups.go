@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// GroupStatus reports the outcome of a named check group.
+type GroupStatus struct {
+	Name   string  `json:"name"`
+	Mode   string  `json:"mode"`
+	Passed bool    `json:"passed"`
+	Up     int     `json:"up"` // Checks passing (or up) in the group
+	Total  int     `json:"total"`
+	Score  float64 `json:"score,omitempty"` // Weighted share, for score mode
+}
+
+// checkGroup maps a configured group onto its slice of the flattened
+// check list returned by config.GetChecks.
+type checkGroup struct {
+	cfg        config.CheckGroupConfig
+	start, end int
+}
+
+func newCheckGroups(groups []config.CheckGroupConfig) []checkGroup {
+	var out []checkGroup
+	start := 0
+	for _, g := range groups {
+		out = append(out, checkGroup{cfg: g, start: start, end: start + len(g.Checks)})
+		start += len(g.Checks)
+	}
+	return out
+}
+
+// evaluate aggregates the group's checks according to its mode.
+func (g checkGroup) evaluate(up []bool) GroupStatus {
+	gs := GroupStatus{Name: g.cfg.Name, Mode: g.cfg.GetMode()}
+
+	totalWeight, upWeight := 0.0, 0.0
+	for i := g.start; i < g.end && i < len(up); i++ {
+		weight := g.cfg.Checks[i-g.start].GetWeight()
+		totalWeight += weight
+		gs.Total++
+		if up[i] {
+			gs.Up++
+			upWeight += weight
+		}
+	}
+
+	switch gs.Mode {
+	case config.GroupAny:
+		gs.Passed = gs.Up > 0
+	case config.GroupKOfN:
+		gs.Passed = gs.Up >= g.cfg.K
+	case config.GroupScore:
+		if totalWeight > 0 {
+			gs.Score = upWeight / totalWeight
+		}
+		gs.Passed = gs.Score+1e-9 >= g.cfg.ScoreThreshold
+	default:
+		gs.Passed = gs.Up == gs.Total
+	}
+	return gs
+}
+
+// evalGroups evaluates every group and the expression combining them.
+func (p *Policy) evalGroups(up []bool) ([]GroupStatus, bool) {
+	statuses := make([]GroupStatus, len(p.groups))
+	passed := make(map[string]bool, len(p.groups))
+	for i, g := range p.groups {
+		statuses[i] = g.evaluate(up)
+		passed[g.cfg.Name] = statuses[i].Passed
+	}
+	return statuses, p.expr.Eval(passed)
+}
+
+// describeGroups lists the failing groups for Status.Reason.
+func describeGroups(groups []GroupStatus) string {
+	var failed []string
+	for _, g := range groups {
+		if !g.Passed {
+			failed = append(failed, fmt.Sprintf("%s (%s, %d/%d up)", g.Name, g.Mode, g.Up, g.Total))
+		}
+	}
+	if len(failed) == 0 {
+		return "all groups passing"
+	}
+	return "groups failing: " + strings.Join(failed, ", ")
+}
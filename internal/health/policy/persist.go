@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+)
+
+// savedState is the debounce state kept across agent restarts, so a
+// respawn or upgrade continues where the previous process left off
+// instead of deciding health from a single round.
+type savedState struct {
+	SavedAt        time.Time `json:"saved_at"`
+	Mode           string    `json:"mode"`
+	Checks         []string  `json:"checks"` // "type target" of each check, to match the windows
+	State          State     `json:"state"`
+	StateChangedAt time.Time `json:"state_changed_at"`
+	FailCount      int       `json:"fail_count"`
+	RecoverCount   int       `json:"recover_count"`
+	HoldDownUntil  time.Time `json:"hold_down_until"`
+	Rounds         []bool    `json:"rounds,omitempty"`       // Aggregate k-of-n window, oldest first
+	CheckRounds    [][]bool  `json:"check_rounds,omitempty"` // Per-check k-of-n windows
+	FlapPenalty    float64   `json:"flap_penalty,omitempty"`
+	Suppressed     bool      `json:"suppressed,omitempty"`
+}
+
+// SaveState writes the policy's debounce state to path.
+func (p *Policy) SaveState(path string) error {
+	data, err := json.Marshal(p.snapshot())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// RestoreState loads state saved by SaveState, if it exists, is younger
+// than maxAge and was saved for the same health mode. The k-of-n windows
+// are only restored if the checks are unchanged. It reports whether the
+// state was restored and must be called before the first Check.
+func (p *Policy) RestoreState(path string, maxAge time.Duration) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var s savedState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return false, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	age := time.Since(s.SavedAt)
+	if age < 0 || age > maxAge {
+		return false, nil
+	}
+	return p.restore(s), nil
+}
+
+// InheritState carries the debounce state of old over to p on a config
+// reload, under the same conditions as RestoreState. The last status is
+// kept too, so clients see one until p's first round. It must be called
+// before p's first Check.
+func (p *Policy) InheritState(old *Policy) bool {
+	if !p.restore(old.snapshot()) {
+		return false
+	}
+	status := old.GetStatus()
+	p.mu.Lock()
+	p.lastStatus = status
+	p.mu.Unlock()
+	return true
+}
+
+func (p *Policy) snapshot() savedState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := savedState{
+		SavedAt:        time.Now(),
+		Mode:           string(p.mode),
+		Checks:         p.checkLabels(),
+		State:          p.currentState,
+		StateChangedAt: p.stateChangedAt,
+		FailCount:      p.failCount,
+		RecoverCount:   p.recoverCount,
+		HoldDownUntil:  p.holdDownUntil,
+	}
+	if p.roundWindow != nil {
+		s.Rounds = p.roundWindow.values()
+	}
+	for _, w := range p.checkWindows {
+		s.CheckRounds = append(s.CheckRounds, w.values())
+	}
+	if p.damp != nil {
+		s.FlapPenalty = p.damp.penalty
+		s.Suppressed = p.damp.suppressed
+	}
+	return s
+}
+
+func (p *Policy) restore(s savedState) bool {
+	if s.Mode != string(p.mode) {
+		return false
+	}
+	switch s.State {
+	case StateHealthy, StateUnhealthy:
+	default:
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.currentState = s.State
+	p.stateChangedAt = s.StateChangedAt
+	p.failCount = s.FailCount
+	p.recoverCount = s.RecoverCount
+	p.holdDownUntil = s.HoldDownUntil
+
+	if slices.Equal(s.Checks, p.checkLabels()) {
+		if p.k > 0 && p.scope != config.KOfNPerCheck && len(s.Rounds) > 0 {
+			p.roundWindow = restoreWindow(p.n, s.Rounds)
+		}
+		if p.k > 0 && p.scope == config.KOfNPerCheck && len(s.CheckRounds) == len(p.checkers) {
+			p.checkWindows = nil
+			for _, rounds := range s.CheckRounds {
+				p.checkWindows = append(p.checkWindows, restoreWindow(p.n, rounds))
+			}
+		}
+	}
+
+	if p.cfg.Health.Dampening.Enabled() {
+		p.damp = &dampener{
+			cfg:        p.cfg.Health.Dampening,
+			penalty:    s.FlapPenalty,
+			updated:    s.SavedAt,
+			suppressed: s.Suppressed,
+		}
+	}
+	return true
+}
+
+// checkLabels identifies the checks the windows belong to.
+func (p *Policy) checkLabels() []string {
+	labels := make([]string, len(p.checkers))
+	for i, c := range p.checkers {
+		labels[i] = c.Type() + " " + c.Target()
+	}
+	return labels
+}
+
+func restoreWindow(n int, rounds []bool) *window {
+	w := newWindow(n)
+	for _, ok := range rounds {
+		w.push(ok)
+	}
+	return w
+}
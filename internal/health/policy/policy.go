@@ -3,6 +3,9 @@ package policy
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,39 +20,106 @@ const (
 	StateHealthy   State = "healthy"
 	StateUnhealthy State = "unhealthy"
 	StateUnknown   State = "unknown"
+	// StateDegraded is a healthy gateway whose latency or loss crossed the
+	// degraded thresholds. It is reported instead of StateHealthy.
+	StateDegraded State = "degraded"
 )
 
 // Status represents the aggregated health status.
 type Status struct {
-	Healthy       bool             `json:"healthy"`
-	State         State            `json:"state"`
-	Reason        string           `json:"reason"`
-	Mode          string           `json:"mode"`
-	CheckResults  []*checks.Result `json:"check_results"`
-	PassedCount   int              `json:"passed_count"`
-	TotalCount    int              `json:"total_count"`
-	RequiredCount int              `json:"required_count"` // k in k-of-n
-	LastCheck     time.Time        `json:"last_check"`
-	StateChangedAt time.Time       `json:"state_changed_at"`
+	Healthy         bool             `json:"healthy"`
+	State           State            `json:"state"`
+	Reason          string           `json:"reason"`
+	Mode            string           `json:"mode"`
+	CheckResults    []*checks.Result `json:"check_results"`
+	PassedCount     int              `json:"passed_count"`
+	TotalCount      int              `json:"total_count"`
+	RequiredCount   int              `json:"required_count"` // Checks required to pass (or be up) per round
+	RoundPassed     bool             `json:"round_passed"`   // Outcome fed to debounce this round
+	Window          *WindowStatus    `json:"window,omitempty"`
+	Score           float64          `json:"score"`                     // Weighted share of checks passing (or up), 0-1
+	ScoreThreshold  float64          `json:"score_threshold,omitempty"` // 0 means all checks must pass
+	Contributions   []Contribution   `json:"contributions,omitempty"`
+	CriticalFailed  []string         `json:"critical_failed,omitempty"` // Critical checks that failed this round
+	Groups          []GroupStatus    `json:"groups,omitempty"`
+	Expression      string           `json:"expression,omitempty"`
+	LatencyMs       float64          `json:"latency_ms"`                // Mean latency of passing checks over the quality window
+	LossPct         float64          `json:"loss_pct"`                  // Mean loss over the quality window: ping packet loss, else failed checks
+	DegradedLevel   int              `json:"degraded_level,omitempty"`  // 0 = not degraded
+	DegradedReason  string           `json:"degraded_reason,omitempty"` // Thresholds crossed
+	FlapPenalty     float64          `json:"flap_penalty,omitempty"`    // Decayed flap dampening penalty
+	Suppressed      bool             `json:"suppressed,omitempty"`      // Flap dampening holds the state
+	ReuseInSec      int              `json:"reuse_in_sec,omitempty"`    // Estimated time until suppression lifts
+	RoundMs         int64            `json:"round_ms"`                  // Time the round of checks took
+	RoundDeadlineMs int64            `json:"round_deadline_ms"`         // Checks still running by then count as timed out
+	LastCheck       time.Time        `json:"last_check"`
+	StateChangedAt  time.Time        `json:"state_changed_at"`
+}
+
+// Contribution is a single check's share of the health score.
+type Contribution struct {
+	Type         string  `json:"type"`
+	Target       string  `json:"target"`
+	Weight       float64 `json:"weight"`
+	Critical     bool    `json:"critical,omitempty"`
+	OK           bool    `json:"ok"`
+	Contribution float64 `json:"contribution"` // weight/total weight if OK, else 0
+}
+
+// WindowStatus reports the k-of-n sliding window contents.
+type WindowStatus struct {
+	Scope  string        `json:"scope"`
+	K      int           `json:"k"`
+	N      int           `json:"n"`
+	Rounds []bool        `json:"rounds,omitempty"` // aggregate scope, oldest first
+	Checks []CheckWindow `json:"checks,omitempty"` // per_check scope
+}
+
+// CheckWindow is the window of a single check in per_check scope.
+type CheckWindow struct {
+	Type    string `json:"type"`
+	Target  string `json:"target"`
+	History []bool `json:"history"` // oldest first
+	Up      bool   `json:"up"`      // at least k of the last n passed
 }
 
 // Policy handles health check aggregation and debouncing.
 type Policy struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex // Guards the state below, held only to update it
+	round sync.Mutex   // Serializes Check rounds
+
+	cfg       *config.Config
+	checkers  []checks.Checker
+	checkCfgs []config.CheckConfig // Index-aligned with checkers
+	mode      config.HealthMode
 
-	cfg      *config.Config
-	checkers []checks.Checker
-	mode     config.HealthMode
+	// Check groups and the expression combining them (nil without groups)
+	groups []checkGroup
+	expr   *config.Expr
 
-	// k-of-n parameters (0 means all-of-n)
-	k int
-	n int
+	// Latency and loss history for the degraded state
+	quality *qualityWindow
+
+	// Flap dampening, nil when disabled
+	damp *dampener
+
+	// Runs checks with their own interval, nil until Start
+	sched *scheduler
+
+	// k-of-n parameters (0 means all-of-n, no window)
+	k     int
+	n     int
+	scope string
+
+	// Sliding windows over the last n rounds
+	roundWindow  *window
+	checkWindows []*window
 
 	// Debounce state
-	failCount    int
-	recoverCount int
-	currentState State
-	lastStatus   *Status
+	failCount      int
+	recoverCount   int
+	currentState   State
+	lastStatus     *Status
 	stateChangedAt time.Time
 
 	// Hold-down timer
@@ -71,6 +141,10 @@ func NewPolicy(cfg *config.Config) (*Policy, error) {
 	}
 	p.k = k
 	p.n = n
+	p.scope = cfg.Health.KOfNScope
+	if p.scope == "" {
+		p.scope = config.KOfNAggregate
+	}
 
 	// Create checkers
 	checkConfigs := cfg.GetChecks()
@@ -79,70 +153,329 @@ func NewPolicy(cfg *config.Config) (*Policy, error) {
 		return nil, err
 	}
 	p.checkers = checkers
+	p.checkCfgs = checkConfigs
+
+	// Build check groups
+	expr, err := cfg.GetExpression()
+	if err != nil {
+		return nil, fmt.Errorf("health.expression: %w", err)
+	}
+	p.expr = expr
+	p.groups = newCheckGroups(cfg.Health.Groups)
 
 	return p, nil
 }
 
 // Check performs a health check and returns the current status.
 func (p *Policy) Check(ctx context.Context) *Status {
+	p.round.Lock()
+	defer p.round.Unlock()
+
+	// The checks run without p.mu, so status readers aren't blocked
+	// for the whole round
+	p.mu.RLock()
+	sched := p.sched
+	p.mu.RUnlock()
+
+	// Run all checks, bounded by the round deadline
+	deadline := p.cfg.RoundDeadline()
+	roundCtx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	// Checks with their own interval contribute their latest result once
+	// the scheduler runs them
+	var idx []int
+	var due []checks.Checker
+	for i, checker := range p.checkers {
+		if sched == nil || p.checkCfgs[i].Interval == 0 {
+			idx = append(idx, i)
+			due = append(due, checker)
+		}
+	}
+
+	start := time.Now()
+	ran := checks.RunAll(roundCtx, due, p.cfg.CheckWorkers())
+	results := make([]*checks.Result, len(p.checkers))
+	for j, i := range idx {
+		results[i] = ran[j]
+	}
+	now := time.Now()
+	for i := range results {
+		if results[i] == nil {
+			results[i] = sched.result(i, p.checkCfgs[i].GetMaxAge(), now)
+		}
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	status := p.update(results)
+	status.RoundMs = time.Since(start).Milliseconds()
+	status.RoundDeadlineMs = deadline.Milliseconds()
+	return status
+}
 
-	// Run all checks
-	results := checks.RunAll(ctx, p.checkers)
-
+// update feeds one round of results through the k-of-n window, health
+// score and debounce logic. The caller must hold p.mu.
+func (p *Policy) update(results []*checks.Result) *Status {
 	// Count passed checks
-	passed := 0
+	passedCount := 0
 	for _, r := range results {
 		if r.OK {
-			passed++
+			passedCount++
 		}
 	}
-
 	total := len(results)
-	required := p.k
-	if required == 0 {
-		// all-of-n mode
-		required = total
-	}
 
 	// Determine if this check round passed
-	roundPassed := passed >= required
+	up, window := p.checkStates(results)
+	score, contributions := p.score(results, up)
+	var groups []GroupStatus
+	passed := p.checksPassed(up, score)
+	if p.expr != nil {
+		groups, passed = p.evalGroups(up)
+	}
+	roundPassed := p.windowRound(passed, window)
+
+	// A failing critical check skips the fail_count debounce
+	var criticalFailed []string
+	for i, r := range results {
+		if !r.OK && p.checkCfg(i).Critical {
+			criticalFailed = append(criticalFailed, fmt.Sprintf("%s %s", r.Type, r.Target))
+		}
+	}
 
-	// Apply debounce logic
-	newState := p.applyDebounce(roundPassed)
+	// Apply debounce logic; a flapping gateway keeps its state while
+	// dampening suppresses changes
+	now := time.Now()
+	if p.damp == nil && p.cfg.Health.Dampening.Enabled() {
+		p.damp = &dampener{cfg: p.cfg.Health.Dampening}
+	}
+	prevState := p.currentState
+	var newState State
+	if p.damp != nil && p.damp.suppressing(now) {
+		newState = p.currentState
+	} else if len(criticalFailed) > 0 {
+		newState = p.applyCritical()
+	} else {
+		newState = p.applyDebounce(roundPassed)
+	}
+	if p.damp != nil && prevState != StateUnknown && newState != prevState {
+		p.damp.flap(now)
+	}
+
+	// Grade link quality; only a healthy gateway can be degraded
+	if p.quality == nil {
+		p.quality = newQualityWindow(p.cfg.Health.Degraded.Window)
+	}
+	p.quality.push(results)
+	latency, loss := p.quality.averages()
+	var degradedLevel int
+	var degradedReason string
+	if newState == StateHealthy && p.cfg.Health.Degraded.Enabled() {
+		degradedLevel, degradedReason = degradation(p.cfg.Health.Degraded, latency, loss)
+	}
+	reported := newState
+	if degradedLevel > 0 {
+		reported = StateDegraded
+	}
 
 	status := &Status{
-		Healthy:       newState == StateHealthy,
-		State:         newState,
-		Mode:          string(p.mode),
-		CheckResults:  results,
-		PassedCount:   passed,
-		TotalCount:    total,
-		RequiredCount: required,
-		LastCheck:     time.Now(),
+		Healthy:        newState == StateHealthy,
+		State:          reported,
+		Mode:           string(p.mode),
+		CheckResults:   results,
+		PassedCount:    passedCount,
+		TotalCount:     total,
+		RequiredCount:  total,
+		RoundPassed:    roundPassed && len(criticalFailed) == 0,
+		Window:         window,
+		Score:          score,
+		ScoreThreshold: p.cfg.Health.ScoreThreshold,
+		Contributions:  contributions,
+		CriticalFailed: criticalFailed,
+		Groups:         groups,
+		LatencyMs:      latency,
+		LossPct:        loss,
+		DegradedLevel:  degradedLevel,
+		DegradedReason: degradedReason,
+		LastCheck:      time.Now(),
 		StateChangedAt: p.stateChangedAt,
 	}
 
+	if p.expr != nil {
+		status.Expression = p.expr.String()
+	}
+	if p.damp != nil {
+		status.FlapPenalty = math.Round(p.damp.penalty)
+		status.Suppressed = p.damp.suppressed
+		status.ReuseInSec = int(p.damp.reuseIn().Round(time.Second) / time.Second)
+	}
+
 	// Set reason
-	if newState == StateHealthy {
+	partial := p.k > 0 || status.ScoreThreshold > 0 || p.expr != nil
+	if degradedLevel > 0 {
+		status.Reason = fmt.Sprintf("degraded (%s)", degradedReason)
+	} else if newState == StateHealthy {
 		status.Reason = "all checks passing"
-		if p.k > 0 {
-			status.Reason = "enough checks passing (k-of-n)"
+		if partial {
+			status.Reason = "enough checks passing"
 		}
 	} else if newState == StateUnhealthy {
 		status.Reason = "checks failing"
-		if p.k > 0 {
-			status.Reason = "not enough checks passing (k-of-n)"
+		if partial {
+			status.Reason = "not enough checks passing"
 		}
 	} else {
 		status.Reason = "initializing"
 	}
+	if len(criticalFailed) > 0 {
+		status.Reason = fmt.Sprintf("critical check failed: %s", strings.Join(criticalFailed, ", "))
+	} else {
+		if groups != nil {
+			status.Reason += fmt.Sprintf(": %s", describeGroups(groups))
+		}
+		if status.ScoreThreshold > 0 {
+			status.Reason += fmt.Sprintf(": score %.2f (threshold %.2f)", score, status.ScoreThreshold)
+		}
+		if window != nil {
+			status.Reason += fmt.Sprintf(": %s", window.describe())
+		}
+	}
+	if status.Suppressed {
+		status.Reason = fmt.Sprintf("flapping, state held (penalty %.0f, reuse below %.0f in ~%ds): %s",
+			status.FlapPenalty, p.cfg.Health.Dampening.GetReuse(), status.ReuseInSec, status.Reason)
+	}
 
 	p.lastStatus = status
 	return status
 }
 
+// checkCfg returns the config of the i-th check, or defaults if unknown.
+func (p *Policy) checkCfg(i int) config.CheckConfig {
+	if i < len(p.checkCfgs) {
+		return p.checkCfgs[i]
+	}
+	return config.CheckConfig{}
+}
+
+// checkStates returns whether each check counts as passing this round:
+// its raw result, or in per_check k-of-n scope, its window state.
+func (p *Policy) checkStates(results []*checks.Result) ([]bool, *WindowStatus) {
+	up := make([]bool, len(results))
+	for i, r := range results {
+		up[i] = r.OK
+	}
+
+	if p.k == 0 {
+		return up, nil
+	}
+
+	ws := &WindowStatus{Scope: p.scope, K: p.k, N: p.n}
+	if p.scope != config.KOfNPerCheck {
+		return up, ws
+	}
+
+	for len(p.checkWindows) < len(results) {
+		p.checkWindows = append(p.checkWindows, newWindow(p.n))
+	}
+	for i, r := range results {
+		w := p.checkWindows[i]
+		w.push(r.OK)
+		up[i] = w.satisfies(p.k)
+		ws.Checks = append(ws.Checks, CheckWindow{
+			Type:    r.Type,
+			Target:  r.Target,
+			History: w.values(),
+			Up:      up[i],
+		})
+	}
+	return up, ws
+}
+
+// score computes the weighted share of passing checks.
+func (p *Policy) score(results []*checks.Result, up []bool) (float64, []Contribution) {
+	totalWeight := 0.0
+	for i := range results {
+		totalWeight += p.checkCfg(i).GetWeight()
+	}
+
+	score := 0.0
+	contributions := make([]Contribution, len(results))
+	for i, r := range results {
+		cfg := p.checkCfg(i)
+		c := Contribution{
+			Type:     r.Type,
+			Target:   r.Target,
+			Weight:   cfg.GetWeight(),
+			Critical: cfg.Critical,
+			OK:       up[i],
+		}
+		if up[i] && totalWeight > 0 {
+			c.Contribution = c.Weight / totalWeight
+			score += c.Contribution
+		}
+		contributions[i] = c
+	}
+	if totalWeight == 0 {
+		score = 1
+	}
+	return score, contributions
+}
+
+// checksPassed applies the flat (group-less) round criterion: every check
+// must pass unless a score threshold is set.
+func (p *Policy) checksPassed(up []bool, score float64) bool {
+	if threshold := p.cfg.Health.ScoreThreshold; threshold > 0 {
+		// Tolerate float rounding when every check passes
+		return score+1e-9 >= threshold
+	}
+	for _, ok := range up {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// windowRound feeds the round outcome through the aggregate k-of-n
+// window, if one is configured, and returns the windowed outcome.
+func (p *Policy) windowRound(passed bool, ws *WindowStatus) bool {
+	if ws == nil || p.scope == config.KOfNPerCheck {
+		return passed
+	}
+
+	if p.roundWindow == nil {
+		p.roundWindow = newWindow(p.n)
+	}
+	p.roundWindow.push(passed)
+	ws.Rounds = p.roundWindow.values()
+	return p.roundWindow.satisfies(p.k)
+}
+
+// describe summarizes the window for Status.Reason.
+func (ws *WindowStatus) describe() string {
+	if ws.Scope == config.KOfNPerCheck {
+		var down []string
+		for _, c := range ws.Checks {
+			if !c.Up {
+				down = append(down, fmt.Sprintf("%s %s", c.Type, c.Target))
+			}
+		}
+		if len(down) == 0 {
+			return fmt.Sprintf("all checks passed >= %d of last %d", ws.K, ws.N)
+		}
+		return fmt.Sprintf("below %d of last %d: %s", ws.K, ws.N, strings.Join(down, ", "))
+	}
+
+	passed := 0
+	for _, ok := range ws.Rounds {
+		if ok {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d of last %d rounds passed (need %d of %d)", passed, len(ws.Rounds), ws.K, ws.N)
+}
+
 func (p *Policy) applyDebounce(roundPassed bool) State {
 	now := time.Now()
 
@@ -208,6 +541,23 @@ func (p *Policy) applyDebounce(roundPassed bool) State {
 	return p.currentState
 }
 
+// applyCritical transitions straight to unhealthy after a critical check
+// failure, bypassing fail_count. The hold-down period is still honored.
+func (p *Policy) applyCritical() State {
+	now := time.Now()
+	if now.Before(p.holdDownUntil) {
+		return p.currentState
+	}
+
+	p.recoverCount = 0
+	p.failCount = 0
+	if p.currentState != StateUnhealthy {
+		p.currentState = StateUnhealthy
+		p.stateChangedAt = now
+	}
+	return p.currentState
+}
+
 // GetStatus returns the last known status.
 func (p *Policy) GetStatus() *Status {
 	p.mu.RLock()
@@ -215,6 +565,11 @@ func (p *Policy) GetStatus() *Status {
 	return p.lastStatus
 }
 
+// Mode returns the health mode the policy was built for.
+func (p *Policy) Mode() config.HealthMode {
+	return p.mode
+}
+
 // GetState returns the current state.
 func (p *Policy) GetState() State {
 	p.mu.RLock()
@@ -238,4 +593,8 @@ func (p *Policy) Reset() {
 	p.currentState = StateUnknown
 	p.lastStatus = nil
 	p.holdDownUntil = time.Time{}
+	p.roundWindow = nil
+	p.checkWindows = nil
+	p.quality = nil
+	p.damp = nil
 }
@@ -2,10 +2,15 @@ package policy
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/zczy-k/FloatingGateway/internal/config"
+	"github.com/zczy-k/FloatingGateway/internal/health/checks"
 )
 
 func TestDebounce_FailCount(t *testing.T) {
@@ -151,76 +156,688 @@ func TestDebounce_SuccessResetsFailCount(t *testing.T) {
 	}
 }
 
-func TestKOfN_2of3(t *testing.T) {
-	cfg := &config.Config{
-		Health: config.HealthConfig{
-			Mode:         config.HealthModeBasic,
-			FailCount:    1,
-			RecoverCount: 1,
-			KOfN:         "2/3",
+// results builds a round of check results with the given outcomes.
+func results(oks ...bool) []*checks.Result {
+	out := make([]*checks.Result, len(oks))
+	for i, ok := range oks {
+		out[i] = &checks.Result{Type: "tcp", Target: fmt.Sprintf("10.0.0.%d:53", i+1), OK: ok}
+	}
+	return out
+}
+
+func newWindowPolicy(kofn, scope string) *Policy {
+	k, n, _ := config.ParseKOfN(kofn)
+	return &Policy{
+		cfg: &config.Config{
+			Health: config.HealthConfig{
+				Mode:         config.HealthModeBasic,
+				FailCount:    1,
+				RecoverCount: 1,
+				KOfN:         kofn,
+				KOfNScope:    scope,
+			},
 		},
+		mode:         config.HealthModeBasic,
+		currentState: StateHealthy,
+		k:            k,
+		n:            n,
+		scope:        scope,
 	}
+}
 
-	p := &Policy{
-		cfg:          cfg,
-		mode:         cfg.Health.Mode,
-		currentState: StateUnknown,
-		k:            2,
-		n:            3,
+func TestKOfN_AggregateWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		kofn   string
+		rounds [][]bool // check outcomes per round
+		want   []bool   // round passed after each round
+	}{
+		{
+			name:   "single failed round tolerated by 2/3",
+			kofn:   "2/3",
+			rounds: [][]bool{{true, true}, {false, true}, {true, true}},
+			want:   []bool{true, true, true},
+		},
+		{
+			name:   "two failed rounds in window fail 2/3",
+			kofn:   "2/3",
+			rounds: [][]bool{{true, true}, {false, true}, {true, false}},
+			want:   []bool{true, true, false},
+		},
+		{
+			name:   "failure slides out of the window",
+			kofn:   "2/3",
+			rounds: [][]bool{{false}, {false}, {true}, {true}, {true}},
+			want:   []bool{true, false, false, true, true},
+		},
+		{
+			name:   "any failed check fails the round",
+			kofn:   "3/3",
+			rounds: [][]bool{{true, true, true}, {true, false, true}},
+			want:   []bool{true, false},
+		},
+		{
+			name:   "1/1 behaves like per-round all-of-n",
+			kofn:   "1/1",
+			rounds: [][]bool{{true}, {false}, {true}},
+			want:   []bool{true, false, true},
+		},
 	}
 
-	// Simulate check with 2 passed out of 3 - should be healthy
-	// We need to test the aggregation logic
-	required := p.k
-	if required == 0 {
-		required = 3 // all-of-n
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newWindowPolicy(tt.kofn, config.KOfNAggregate)
+			for i, round := range tt.rounds {
+				status := p.update(results(round...))
+				if status.RoundPassed != tt.want[i] {
+					t.Errorf("round %d: RoundPassed = %v, want %v (window %v)", i+1, status.RoundPassed, tt.want[i], status.Window.Rounds)
+				}
+			}
+		})
 	}
+}
 
-	passed := 2
-	roundPassed := passed >= required
+func TestKOfN_PerCheckWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		kofn   string
+		rounds [][]bool
+		want   []bool
+		wantUp []bool // per-check up state after the last round
+	}{
+		{
+			name:   "alternating failures on different checks stay up",
+			kofn:   "2/3",
+			rounds: [][]bool{{false, true}, {true, false}, {true, true}},
+			want:   []bool{true, true, true},
+			wantUp: []bool{true, true},
+		},
+		{
+			name:   "one check failing twice goes down",
+			kofn:   "2/3",
+			rounds: [][]bool{{false, true}, {false, true}, {true, true}},
+			want:   []bool{true, false, false},
+			wantUp: []bool{false, true},
+		},
+		{
+			name:   "check recovers once failures leave the window",
+			kofn:   "2/3",
+			rounds: [][]bool{{false}, {false}, {true}, {true}},
+			want:   []bool{true, false, false, true},
+			wantUp: []bool{true},
+		},
+	}
 
-	if !roundPassed {
-		t.Error("Expected 2/3 to pass with 2 successes")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newWindowPolicy(tt.kofn, config.KOfNPerCheck)
+			var status *Status
+			for i, round := range tt.rounds {
+				status = p.update(results(round...))
+				if status.RoundPassed != tt.want[i] {
+					t.Errorf("round %d: RoundPassed = %v, want %v", i+1, status.RoundPassed, tt.want[i])
+				}
+			}
+			for i, up := range tt.wantUp {
+				if status.Window.Checks[i].Up != up {
+					t.Errorf("check %d: Up = %v, want %v (history %v)", i, status.Window.Checks[i].Up, up, status.Window.Checks[i].History)
+				}
+			}
+		})
 	}
+}
 
-	// Apply debounce with passing result
-	p.applyDebounce(true)
-	if p.currentState != StateHealthy {
-		t.Errorf("Expected healthy with 2/3, got %s", p.currentState)
+func TestKOfN_WindowStatus(t *testing.T) {
+	p := newWindowPolicy("2/3", config.KOfNAggregate)
+	for _, ok := range []bool{true, false, true, false} {
+		p.update(results(ok))
+	}
+	status := p.GetStatus()
+
+	if status.Window == nil {
+		t.Fatal("Expected window status")
+	}
+	want := []bool{false, true, false} // oldest first, first round evicted
+	if fmt.Sprint(status.Window.Rounds) != fmt.Sprint(want) {
+		t.Errorf("Expected rounds %v, got %v", want, status.Window.Rounds)
+	}
+	if status.Window.K != 2 || status.Window.N != 3 {
+		t.Errorf("Expected k=2 n=3, got k=%d n=%d", status.Window.K, status.Window.N)
+	}
+	if status.State != StateUnhealthy {
+		t.Errorf("Expected unhealthy with 1 of 3 rounds passing, got %s", status.State)
+	}
+}
+
+func TestAllOfN_NoWindow(t *testing.T) {
+	p := newWindowPolicy("", "")
+	status := p.update(results(true, false))
+	if status.RoundPassed {
+		t.Error("Expected round to fail when a check fails without k_of_n")
+	}
+	if status.Window != nil {
+		t.Error("Expected no window without k_of_n")
+	}
+}
+
+func newScorePolicy(threshold float64, checkCfgs ...config.CheckConfig) *Policy {
+	return &Policy{
+		cfg: &config.Config{
+			Health: config.HealthConfig{
+				Mode:           config.HealthModeBasic,
+				FailCount:      3,
+				RecoverCount:   1,
+				ScoreThreshold: threshold,
+			},
+		},
+		checkCfgs:    checkCfgs,
+		mode:         config.HealthModeBasic,
+		currentState: StateHealthy,
+	}
+}
+
+func TestScoreThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		weights   []float64
+		oks       []bool
+		wantScore float64
+		wantPass  bool
+	}{
+		{
+			name:      "all passing",
+			threshold: 0.6,
+			weights:   []float64{1, 1, 1},
+			oks:       []bool{true, true, true},
+			wantScore: 1,
+			wantPass:  true,
+		},
+		{
+			name:      "heavy check carries the round",
+			threshold: 0.6,
+			weights:   []float64{3, 1, 1},
+			oks:       []bool{true, false, false},
+			wantScore: 0.6,
+			wantPass:  true,
+		},
+		{
+			name:      "heavy check failing drops below threshold",
+			threshold: 0.6,
+			weights:   []float64{3, 1, 1},
+			oks:       []bool{false, true, true},
+			wantScore: 0.4,
+			wantPass:  false,
+		},
+		{
+			name:      "unset weight defaults to 1",
+			threshold: 0.5,
+			weights:   []float64{0, 0},
+			oks:       []bool{true, false},
+			wantScore: 0.5,
+			wantPass:  true,
+		},
+		{
+			name:      "no threshold requires every check",
+			threshold: 0,
+			weights:   []float64{3, 1},
+			oks:       []bool{true, false},
+			wantScore: 0.75,
+			wantPass:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfgs []config.CheckConfig
+			for _, w := range tt.weights {
+				cfgs = append(cfgs, config.CheckConfig{Type: "tcp", Weight: w})
+			}
+			p := newScorePolicy(tt.threshold, cfgs...)
+			status := p.update(results(tt.oks...))
+			if math.Abs(status.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Score = %v, want %v", status.Score, tt.wantScore)
+			}
+			if status.RoundPassed != tt.wantPass {
+				t.Errorf("RoundPassed = %v, want %v", status.RoundPassed, tt.wantPass)
+			}
+			if len(status.Contributions) != len(tt.oks) {
+				t.Fatalf("Expected %d contributions, got %d", len(tt.oks), len(status.Contributions))
+			}
+		})
+	}
+}
+
+func TestCriticalCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		critical  []bool
+		oks       []bool
+		wantState State
+	}{
+		{
+			name:      "critical failure bypasses fail_count",
+			critical:  []bool{true, false},
+			oks:       []bool{false, true},
+			wantState: StateUnhealthy,
+		},
+		{
+			name:      "non-critical failure is debounced",
+			critical:  []bool{true, false},
+			oks:       []bool{true, false},
+			wantState: StateHealthy,
+		},
+		{
+			name:      "critical failure wins over score threshold",
+			critical:  []bool{false, false, true},
+			oks:       []bool{true, true, false},
+			wantState: StateUnhealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfgs []config.CheckConfig
+			for _, c := range tt.critical {
+				cfgs = append(cfgs, config.CheckConfig{Type: "tcp", Critical: c})
+			}
+			p := newScorePolicy(0.5, cfgs...)
+			status := p.update(results(tt.oks...))
+			if status.State != tt.wantState {
+				t.Errorf("State = %s, want %s (reason %q)", status.State, tt.wantState, status.Reason)
+			}
+			if tt.wantState == StateUnhealthy && !strings.HasPrefix(status.Reason, "critical check failed") {
+				t.Errorf("Expected critical reason, got %q", status.Reason)
+			}
+		})
+	}
+}
+
+func TestCriticalCheck_HoldDown(t *testing.T) {
+	p := newScorePolicy(0, config.CheckConfig{Type: "tcp", Critical: true})
+	p.holdDownUntil = time.Now().Add(time.Minute)
+
+	status := p.update(results(false))
+	if status.State != StateHealthy {
+		t.Errorf("Expected critical failure to respect hold-down, got %s", status.State)
 	}
 }
 
-func TestKOfN_1of3_Fails(t *testing.T) {
+// newGroupPolicy builds a policy evaluating the given check groups.
+func newGroupPolicy(t *testing.T, expression string, groups ...config.CheckGroupConfig) *Policy {
+	t.Helper()
 	cfg := &config.Config{
 		Health: config.HealthConfig{
 			Mode:         config.HealthModeBasic,
 			FailCount:    1,
 			RecoverCount: 1,
-			KOfN:         "2/3",
+			Groups:       groups,
+			Expression:   expression,
 		},
 	}
-
-	p := &Policy{
+	expr, err := cfg.GetExpression()
+	if err != nil {
+		t.Fatalf("GetExpression: %v", err)
+	}
+	return &Policy{
 		cfg:          cfg,
-		mode:         cfg.Health.Mode,
+		checkCfgs:    cfg.GetChecks(),
+		mode:         config.HealthModeBasic,
 		currentState: StateHealthy,
-		k:            2,
-		n:            3,
+		groups:       newCheckGroups(groups),
+		expr:         expr,
+	}
+}
+
+func group(name, mode string, size int) config.CheckGroupConfig {
+	g := config.CheckGroupConfig{Name: name, Mode: mode}
+	for i := 0; i < size; i++ {
+		g.Checks = append(g.Checks, config.CheckConfig{Type: "tcp"})
+	}
+	return g
+}
+
+func TestGroups(t *testing.T) {
+	kOfN := group("foreign", config.GroupKOfN, 3)
+	kOfN.K = 2
+	score := group("foreign", config.GroupScore, 3)
+	score.ScoreThreshold = 0.5
+	score.Checks[0].Weight = 2
+
+	tests := []struct {
+		name       string
+		expression string
+		groups     []config.CheckGroupConfig
+		oks        []bool
+		want       bool
+		wantFailed string // substring expected in Reason
+	}{
+		{
+			name:       "uplink and any foreign site",
+			expression: "uplink && foreign",
+			groups:     []config.CheckGroupConfig{group("uplink", "", 1), group("foreign", config.GroupAny, 3)},
+			oks:        []bool{true, false, false, true},
+			want:       true,
+		},
+		{
+			name:       "uplink down fails",
+			expression: "uplink && foreign",
+			groups:     []config.CheckGroupConfig{group("uplink", "", 1), group("foreign", config.GroupAny, 3)},
+			oks:        []bool{false, true, true, true},
+			want:       false,
+			wantFailed: "uplink (all, 0/1 up)",
+		},
+		{
+			name:       "no foreign site fails",
+			expression: "uplink and foreign",
+			groups:     []config.CheckGroupConfig{group("uplink", "", 1), group("foreign", config.GroupAny, 3)},
+			oks:        []bool{true, false, false, false},
+			want:       false,
+			wantFailed: "foreign (any, 0/3 up)",
+		},
+		{
+			name:   "default expression requires every group",
+			groups: []config.CheckGroupConfig{group("a", "", 1), group("b", "", 1)},
+			oks:    []bool{true, false},
+			want:   false,
+		},
+		{
+			name:       "or with parentheses",
+			expression: "uplink && (domestic || foreign)",
+			groups:     []config.CheckGroupConfig{group("uplink", "", 1), group("domestic", "", 1), group("foreign", "", 1)},
+			oks:        []bool{true, false, true},
+			want:       true,
+		},
+		{
+			name:       "negation",
+			expression: "uplink && !maintenance",
+			groups:     []config.CheckGroupConfig{group("uplink", "", 1), group("maintenance", "", 1)},
+			oks:        []bool{true, true},
+			want:       false,
+		},
+		{
+			name:       "k_of_n group",
+			expression: "foreign",
+			groups:     []config.CheckGroupConfig{kOfN},
+			oks:        []bool{true, false, true},
+			want:       true,
+		},
+		{
+			name:       "score group",
+			expression: "foreign",
+			groups:     []config.CheckGroupConfig{score},
+			oks:        []bool{false, true, true},
+			want:       true,
+		},
 	}
 
-	// With k=2, only 1 passing should fail
-	passed := 1
-	required := p.k
-	roundPassed := passed >= required
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newGroupPolicy(t, tt.expression, tt.groups...)
+			status := p.update(results(tt.oks...))
+			if status.RoundPassed != tt.want {
+				t.Errorf("RoundPassed = %v, want %v (reason %q)", status.RoundPassed, tt.want, status.Reason)
+			}
+			if len(status.Groups) != len(tt.groups) {
+				t.Errorf("Expected %d group statuses, got %d", len(tt.groups), len(status.Groups))
+			}
+			if tt.wantFailed != "" && !strings.Contains(status.Reason, tt.wantFailed) {
+				t.Errorf("Expected reason to mention %q, got %q", tt.wantFailed, status.Reason)
+			}
+		})
+	}
+}
 
-	if roundPassed {
-		t.Error("Expected 1/3 to fail with k=2")
+func TestGroups_InvalidExpression(t *testing.T) {
+	for _, expr := range []string{"a &&", "(a || b", "a b", "a & b", "&& a"} {
+		if _, err := config.ParseExpr(expr); err == nil {
+			t.Errorf("Expected error parsing %q", expr)
+		}
 	}
+}
 
-	// Apply debounce with failing result
-	p.applyDebounce(false)
-	if p.currentState != StateUnhealthy {
-		t.Errorf("Expected unhealthy with only 1/3, got %s", p.currentState)
+// latencies builds a round of passing results with the given latencies;
+// a negative latency marks a failed check.
+func latencies(ms ...int64) []*checks.Result {
+	out := results(make([]bool, len(ms))...)
+	for i, l := range ms {
+		out[i].OK = l >= 0
+		if l >= 0 {
+			out[i].LatencyMs = l
+		}
+	}
+	return out
+}
+
+func TestDegraded(t *testing.T) {
+	tests := []struct {
+		name      string
+		degraded  config.DegradedConfig
+		rounds    [][]int64
+		wantState State
+		wantLevel int
+	}{
+		{
+			name:      "fast and lossless stays healthy",
+			degraded:  config.DegradedConfig{LatencyMs: 300, LossPct: 10, Window: 3},
+			rounds:    [][]int64{{50, 60}, {40, 70}},
+			wantState: StateHealthy,
+		},
+		{
+			name:      "high latency degrades",
+			degraded:  config.DegradedConfig{LatencyMs: 300, Window: 3},
+			rounds:    [][]int64{{400, 500}},
+			wantState: StateDegraded,
+			wantLevel: 1,
+		},
+		{
+			name:      "twice the latency threshold adds a level",
+			degraded:  config.DegradedConfig{LatencyMs: 300, Window: 3},
+			rounds:    [][]int64{{800, 700}},
+			wantState: StateDegraded,
+			wantLevel: 2,
+		},
+		{
+			name:      "latency averaged over the window",
+			degraded:  config.DegradedConfig{LatencyMs: 300, Window: 3},
+			rounds:    [][]int64{{900}, {100}, {100}, {100}},
+			wantState: StateHealthy,
+		},
+		{
+			name:      "loss and latency both count",
+			degraded:  config.DegradedConfig{LatencyMs: 300, LossPct: 20, Window: 5},
+			rounds:    [][]int64{{400, 400, 400, 400}, {400, 400, 400, -1}},
+			wantState: StateDegraded,
+			wantLevel: 1,
+		},
+		{
+			name:      "loss at twice the threshold",
+			degraded:  config.DegradedConfig{LossPct: 10, Window: 2},
+			rounds:    [][]int64{{10, 10, 10, -1}, {10, 10, -1, 10}},
+			wantState: StateDegraded,
+			wantLevel: 2,
+		},
+		{
+			name:      "disabled thresholds never degrade",
+			degraded:  config.DegradedConfig{Window: 3},
+			rounds:    [][]int64{{5000}},
+			wantState: StateHealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newWindowPolicy("1/5", config.KOfNAggregate)
+			p.cfg.Health.Degraded = tt.degraded
+			var status *Status
+			for _, round := range tt.rounds {
+				status = p.update(latencies(round...))
+			}
+			if status.State != tt.wantState || status.DegradedLevel != tt.wantLevel {
+				t.Errorf("State = %s level %d, want %s level %d (reason %q)",
+					status.State, status.DegradedLevel, tt.wantState, tt.wantLevel, status.Reason)
+			}
+			if !status.Healthy {
+				t.Error("Expected a degraded gateway to still count as healthy")
+			}
+		})
+	}
+}
+
+func TestDegraded_MeasuredLoss(t *testing.T) {
+	p := newWindowPolicy("1/5", config.KOfNAggregate)
+	p.cfg.Health.Degraded = config.DegradedConfig{LossPct: 10, Window: 1}
+
+	// The ping passes with 3 of 10 probes lost, the tcp check passes
+	round := []*checks.Result{
+		{Type: "ping", Target: "1.1.1.1", OK: true, LatencyMs: 20, Sent: 10, LossPct: 30},
+		{Type: "tcp", Target: "1.1.1.1:443", OK: true, LatencyMs: 20},
+	}
+	status := p.update(round)
+	if status.LossPct != 15 || status.State != StateDegraded || status.DegradedLevel != 1 {
+		t.Errorf("loss %.1f%% state %s level %d, want 15%% degraded level 1", status.LossPct, status.State, status.DegradedLevel)
+	}
+
+	// Without ping statistics a failed check counts as fully lost
+	round[0] = &checks.Result{Type: "ping", Target: "1.1.1.1", ErrorCode: "PING_FAILED"}
+	status = p.update(round)
+	if status.LossPct != 50 {
+		t.Errorf("loss %.1f%%, want 50%%", status.LossPct)
+	}
+}
+
+func TestDegraded_UnhealthyWins(t *testing.T) {
+	p := newWindowPolicy("", "")
+	p.cfg.Health.Degraded = config.DegradedConfig{LatencyMs: 100, Window: 3}
+	status := p.update(latencies(500, -1))
+	if status.State != StateUnhealthy || status.DegradedLevel != 0 {
+		t.Errorf("Expected unhealthy without degradation, got %s level %d", status.State, status.DegradedLevel)
+	}
+}
+
+func TestTrackPenalty(t *testing.T) {
+	cfg := config.DefaultConfig() // Priorities 100/150: track weight -75
+	tests := []struct {
+		healthy bool
+		level   int
+		want    int
+	}{
+		{true, 0, 0},
+		{true, 1, 18},
+		{true, 3, 54},
+		{true, 10, 74}, // Capped below the unhealthy penalty
+		{false, 0, 75},
+	}
+	for _, tt := range tests {
+		if got := cfg.TrackPenalty(tt.healthy, tt.level); got != tt.want {
+			t.Errorf("TrackPenalty(%v, %d) = %d, want %d", tt.healthy, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestDampening(t *testing.T) {
+	p := newWindowPolicy("", "")
+	p.cfg.Health.Dampening = config.DampeningConfig{Suppress: 2500, Reuse: 750, Penalty: 1000, HalfLifeSec: 60}
+
+	// Three flaps in quick succession cross the suppress threshold
+	for i, ok := range []bool{false, true, false} {
+		status := p.update(results(ok))
+		if status.Healthy != ok {
+			t.Fatalf("Round %d: expected healthy=%v before suppression, got %s", i, ok, status.State)
+		}
+	}
+	status := p.update(results(true))
+	if status.Healthy || !status.Suppressed {
+		t.Fatalf("Expected the unhealthy state to be held, got %s (suppressed %v)", status.State, status.Suppressed)
+	}
+	if status.FlapPenalty < 2500 || status.ReuseInSec == 0 {
+		t.Errorf("Expected penalty >= 2500 with a reuse estimate, got %v (reuse in %ds)", status.FlapPenalty, status.ReuseInSec)
+	}
+
+	// Three half-lives later the penalty is below reuse and changes resume
+	p.damp.updated = p.damp.updated.Add(-3 * time.Minute)
+	status = p.update(results(true))
+	if !status.Healthy || status.Suppressed {
+		t.Errorf("Expected recovery once the penalty decayed, got %s (suppressed %v)", status.State, status.Suppressed)
+	}
+}
+
+func TestDampening_Disabled(t *testing.T) {
+	p := newWindowPolicy("", "")
+	for i := 0; i < 10; i++ {
+		ok := i%2 == 1
+		if status := p.update(results(ok)); status.Healthy != ok || status.FlapPenalty != 0 {
+			t.Fatalf("Round %d: expected healthy=%v without a penalty, got %s (penalty %v)", i, ok, status.State, status.FlapPenalty)
+		}
+	}
+}
+
+func TestSaveRestoreState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.state")
+
+	p := newWindowPolicy("2/3", config.KOfNAggregate)
+	p.cfg.Health.FailCount = 3
+	p.update(results(true))
+	p.update(results(false))
+	p.update(results(false))
+	p.holdDownUntil = time.Now().Add(time.Minute)
+	if err := p.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	restored := newWindowPolicy("2/3", config.KOfNAggregate)
+	restored.currentState = StateUnknown
+	ok, err := restored.RestoreState(path, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("RestoreState = %v, %v; want restored", ok, err)
+	}
+	if restored.currentState != StateHealthy || restored.failCount != p.failCount || !restored.holdDownUntil.Equal(p.holdDownUntil) {
+		t.Errorf("Restored state %s fail count %d, want %s fail count %d with hold-down",
+			restored.currentState, restored.failCount, p.currentState, p.failCount)
+	}
+	if got := restored.roundWindow.values(); len(got) != 3 || got[0] != true || got[2] != false {
+		t.Errorf("Restored window = %v, want [true false false]", got)
+	}
+
+	// Stale state, or state of another mode, is ignored
+	stale := newWindowPolicy("2/3", config.KOfNAggregate)
+	stale.currentState = StateUnknown
+	time.Sleep(10 * time.Millisecond)
+	if ok, _ := stale.RestoreState(path, time.Millisecond); ok || stale.currentState != StateUnknown {
+		t.Error("Expected stale state to be ignored")
+	}
+	other := newWindowPolicy("2/3", config.KOfNAggregate)
+	other.mode = config.HealthModeInternet
+	if ok, _ := other.RestoreState(path, time.Minute); ok {
+		t.Error("Expected state of another mode to be ignored")
+	}
+
+	missing := newWindowPolicy("", "")
+	if ok, err := missing.RestoreState(filepath.Join(t.TempDir(), "none"), time.Minute); ok || err != nil {
+		t.Errorf("RestoreState of a missing file = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestInheritState(t *testing.T) {
+	old := newWindowPolicy("", "")
+	old.update(results(false))
+	if old.currentState != StateUnhealthy {
+		t.Fatalf("Expected unhealthy before the reload, got %s", old.currentState)
+	}
+
+	reloaded := newWindowPolicy("", "")
+	reloaded.currentState = StateUnknown
+	if !reloaded.InheritState(old) || reloaded.currentState != StateUnhealthy {
+		t.Errorf("Reloaded state %s, want unhealthy", reloaded.currentState)
+	}
+	if reloaded.GetStatus() == nil || reloaded.GetStatus() != old.GetStatus() {
+		t.Error("Expected the last status to carry over")
+	}
+
+	other := newWindowPolicy("", "")
+	other.mode = config.HealthModeInternet
+	if other.InheritState(old) {
+		t.Error("Expected state of another mode not to carry over")
 	}
 }
 
@@ -335,3 +952,47 @@ func TestPolicyCheck(t *testing.T) {
 		t.Errorf("Expected TotalCount=1, got %d", status.TotalCount)
 	}
 }
+
+// blockingChecker passes once release is closed.
+type blockingChecker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingChecker) Check(ctx context.Context) *checks.Result {
+	close(c.started)
+	<-c.release
+	return &checks.Result{Type: c.Type(), Target: c.Target(), OK: true}
+}
+
+func (c *blockingChecker) Type() string   { return "stub" }
+func (c *blockingChecker) Target() string { return "slow" }
+
+func TestCheck_StatusReadableDuringRound(t *testing.T) {
+	p := newWindowPolicy("", "")
+	p.cfg.Health.IntervalSec = 10
+	checker := &blockingChecker{started: make(chan struct{}), release: make(chan struct{})}
+	p.checkers = []checks.Checker{checker}
+	p.checkCfgs = []config.CheckConfig{{Type: "stub"}}
+
+	done := make(chan *Status)
+	go func() { done <- p.Check(context.Background()) }()
+	<-checker.started
+
+	read := make(chan struct{})
+	go func() {
+		p.GetStatus()
+		p.GetState()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		t.Error("GetStatus blocked while the checks were running")
+	}
+
+	close(checker.release)
+	if status := <-done; !status.Healthy || p.GetStatus() != status {
+		t.Errorf("Expected the finished round to be healthy and published, got %+v", status)
+	}
+}
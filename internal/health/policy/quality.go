@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zczy-k/FloatingGateway/internal/config"
+	"github.com/zczy-k/FloatingGateway/internal/health/checks"
+)
+
+// qualitySample is the link quality measured in one round.
+type qualitySample struct {
+	latencyMs int64   // Sum of passing check latencies
+	lost      float64 // Sum of per-check loss, 0-1
+	passed    int
+	total     int
+}
+
+// qualityWindow averages latency and loss over the most recent rounds.
+type qualityWindow struct {
+	buf   []qualitySample
+	next  int
+	count int
+}
+
+func newQualityWindow(n int) *qualityWindow {
+	if n < 1 {
+		n = 1
+	}
+	return &qualityWindow{buf: make([]qualitySample, n)}
+}
+
+func (q *qualityWindow) push(results []*checks.Result) {
+	var s qualitySample
+	for _, r := range results {
+		s.total++
+		if r.OK {
+			s.passed++
+			s.latencyMs += r.LatencyMs
+		}
+		// A ping measures its packet loss; any other check is all or nothing
+		switch {
+		case r.Sent > 0:
+			s.lost += r.LossPct / 100
+		case !r.OK:
+			s.lost++
+		}
+	}
+	q.buf[q.next] = s
+	q.next = (q.next + 1) % len(q.buf)
+	if q.count < len(q.buf) {
+		q.count++
+	}
+}
+
+// averages returns the mean latency of passing checks and the mean loss
+// of all checks across the window: the measured packet loss of pings and
+// 100% for any other failed check.
+func (q *qualityWindow) averages() (latencyMs, lossPct float64) {
+	var sum int64
+	var lost float64
+	passed, total := 0, 0
+	for i := 0; i < q.count; i++ {
+		s := q.buf[(q.next-1-i+len(q.buf))%len(q.buf)]
+		sum += s.latencyMs
+		lost += s.lost
+		passed += s.passed
+		total += s.total
+	}
+	if passed > 0 {
+		latencyMs = float64(sum) / float64(passed)
+	}
+	if total > 0 {
+		lossPct = lost * 100 / float64(total)
+	}
+	return latencyMs, lossPct
+}
+
+// degradation grades the measured quality against the thresholds. Each
+// metric adds one level once it crosses its threshold and another at twice
+// the threshold.
+func degradation(d config.DegradedConfig, latencyMs, lossPct float64) (int, string) {
+	level := 0
+	var reasons []string
+	grade := func(value, threshold float64, format string) {
+		if threshold <= 0 || value < threshold {
+			return
+		}
+		level++
+		if value >= 2*threshold {
+			level++
+		}
+		reasons = append(reasons, fmt.Sprintf(format, value, threshold))
+	}
+	grade(latencyMs, float64(d.LatencyMs), "latency %.0fms >= %.0fms")
+	grade(lossPct, d.LossPct, "loss %.0f%% >= %.0f%%")
+	return level, strings.Join(reasons, ", ")
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/zczy-k/FloatingGateway/internal/health/checks"
+)
+
+// scheduler runs the checks that have their own interval, each on its
+// own timer, and keeps their latest results for the rounds to use.
+type scheduler struct {
+	mu     sync.Mutex
+	latest map[int]*checks.Result
+	at     map[int]time.Time
+}
+
+// Start runs the checks with their own interval in the background until
+// ctx is done. Their first results are collected before it returns, so
+// the next round already sees them. Without Start, Check runs every
+// check each round.
+func (p *Policy) Start(ctx context.Context) {
+	var idx []int
+	var checkers []checks.Checker
+	for i, cfg := range p.checkCfgs {
+		if cfg.Interval > 0 {
+			idx = append(idx, i)
+			checkers = append(checkers, p.checkers[i])
+		}
+	}
+	if len(idx) == 0 {
+		return
+	}
+
+	s := &scheduler{latest: make(map[int]*checks.Result), at: make(map[int]time.Time)}
+	started := time.Now()
+	first, cancel := context.WithTimeout(ctx, p.cfg.RoundDeadline())
+	results := checks.RunAll(first, checkers, p.cfg.CheckWorkers())
+	cancel()
+	now := time.Now()
+	for j, i := range idx {
+		s.latest[i] = results[j]
+		s.at[i] = now
+	}
+
+	for _, i := range idx {
+		go s.loop(ctx, i, p.checkers[i], started, p.checkCfgs[i].Interval, p.checkCfgs[i].Jitter)
+	}
+
+	p.mu.Lock()
+	p.sched = s
+	p.mu.Unlock()
+}
+
+// loop runs a check interval seconds after the start of its previous
+// run, plus a random delay of up to jitter seconds.
+func (s *scheduler) loop(ctx context.Context, i int, checker checks.Checker, last time.Time, interval, jitter int) {
+	for {
+		next := last.Add(time.Duration(interval) * time.Second)
+		if jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(time.Duration(jitter) * time.Second))))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		last = time.Now()
+		result := checker.Check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.mu.Lock()
+		s.latest[i] = result
+		s.at[i] = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// result returns a copy of the latest result of the i-th check with its
+// age set. A result older than maxAge is turned into a failure.
+func (s *scheduler) result(i int, maxAge time.Duration, now time.Time) *checks.Result {
+	s.mu.Lock()
+	latest, at := s.latest[i], s.at[i]
+	s.mu.Unlock()
+
+	r := *latest
+	age := now.Sub(at)
+	r.AgeMs = age.Milliseconds()
+	if age > maxAge {
+		r.OK = false
+		r.ErrorCode = "CHECK_STALE"
+		r.Message = fmt.Sprintf("latest result is %s old (max_age %s): %s", age.Round(time.Second), maxAge, latest.Message)
+	}
+	return &r
+}
@@ -0,0 +1,53 @@
+package policy
+
+// window is a fixed-size ring buffer of pass/fail outcomes used for
+// k-of-n evaluation over the most recent n rounds.
+type window struct {
+	buf   []bool
+	next  int
+	count int
+}
+
+func newWindow(n int) *window {
+	if n < 1 {
+		n = 1
+	}
+	return &window{buf: make([]bool, n)}
+}
+
+// push records an outcome, evicting the oldest once full.
+func (w *window) push(ok bool) {
+	w.buf[w.next] = ok
+	w.next = (w.next + 1) % len(w.buf)
+	if w.count < len(w.buf) {
+		w.count++
+	}
+}
+
+// failed returns the number of failures currently in the window.
+func (w *window) failed() int {
+	failed := 0
+	for _, ok := range w.values() {
+		if !ok {
+			failed++
+		}
+	}
+	return failed
+}
+
+// satisfies reports whether at least k of the last n outcomes passed.
+// Slots not filled yet count as passes, so a new window fails only once
+// more than n-k failures have been seen.
+func (w *window) satisfies(k int) bool {
+	return w.failed() <= len(w.buf)-k
+}
+
+// values returns the recorded outcomes, oldest first.
+func (w *window) values() []bool {
+	out := make([]bool, 0, w.count)
+	start := (w.next - w.count + len(w.buf)) % len(w.buf)
+	for i := 0; i < w.count; i++ {
+		out = append(out, w.buf[(start+i)%len(w.buf)])
+	}
+	return out
+}
@@ -25,6 +25,29 @@ func init() {
 	}
 }
 
+// StateFile returns the file the notify scripts record the VRRP state of
+// an instance or sync group in.
+func StateFile(name string) string {
+	return fmt.Sprintf("/tmp/keepalived.%s.state", name)
+}
+
+// ResetStateFiles initializes the state file of every rendered instance
+// and sync group with UNKNOWN, clearing test or stale data.
+func ResetStateFiles(cfg *config.Config) {
+	var names []string
+	for _, inst := range cfg.GetInstances() {
+		names = append(names, inst.Name)
+	}
+	for _, group := range cfg.SyncGroups {
+		names = append(names, group.Name)
+	}
+	for _, name := range names {
+		stateFile := StateFile(name)
+		os.WriteFile(stateFile, []byte("UNKNOWN"), 0666)
+		os.Chmod(stateFile, 0666)
+	}
+}
+
 // FindConfigPath finds the keepalived config file path.
 func FindConfigPath() string {
 	return currentPlatform.FindConfigPath()
@@ -58,6 +81,16 @@ func Apply(cfg *config.Config) error {
 		return fmt.Errorf("rename config: %w", err)
 	}
 
+	// keepalived needs the track file to exist; start out unhealthy
+	// until the daemon has made its first decision.
+	if cfg.Keepalived.Track == config.TrackFile {
+		if _, err := os.Stat(cfg.Keepalived.TrackFile); os.IsNotExist(err) {
+			if err := WriteTrackFile(cfg.Keepalived.TrackFile, cfg.TrackPenalty(false, 0)); err != nil {
+				return fmt.Errorf("write track file: %w", err)
+			}
+		}
+	}
+
 	// Reload keepalived
 	if err := Reload(); err != nil {
 		return fmt.Errorf("reload keepalived: %w", err)
@@ -66,6 +99,27 @@ func Apply(cfg *config.Config) error {
 	return nil
 }
 
+// WriteTrackFile atomically writes the value read by keepalived's
+// vrrp_track_file: the priority penalty, 0 when fully healthy. See
+// config.TrackPenalty.
+func WriteTrackFile(path string, penalty int) error {
+	value := fmt.Sprintf("%d\n", penalty)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(value), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // Reload reloads the keepalived service.
 func Reload() error {
 	return currentPlatform.Reload()
@@ -73,18 +127,39 @@ func Reload() error {
 
 // Status returns the keepalived service status.
 type Status struct {
-	Running     bool   `json:"running"`
-	ConfigPath  string `json:"config_path"`
-	ConfigValid bool   `json:"config_valid"`
-	VRRPState   string `json:"vrrp_state"`
-	Priority    int    `json:"priority"`
-	Error       string `json:"error,omitempty"`
+	Running     bool             `json:"running"`
+	ConfigPath  string           `json:"config_path"`
+	ConfigValid bool             `json:"config_valid"`
+	VRRPState   string           `json:"vrrp_state"` // State of the main GATEWAY instance
+	Priority    int              `json:"priority"`
+	Instances   []InstanceStatus `json:"instances,omitempty"`
+	Groups      []GroupStatus    `json:"sync_groups,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// GroupStatus holds the state of a vrrp_sync_group.
+type GroupStatus struct {
+	Name      string   `json:"name"`
+	Instances []string `json:"instances"`
+	State     string   `json:"state"` // SPLIT if members disagree
+}
+
+// InstanceStatus holds the state of a single vrrp_instance.
+type InstanceStatus struct {
+	Name      string   `json:"name"`
+	Interface string   `json:"interface"`
+	VRID      int      `json:"vrid"`
+	VIPs      []string `json:"vips"`
+	Priority  int      `json:"priority"`
+	Group     string   `json:"sync_group,omitempty"`
+	State     string   `json:"state"`
 }
 
 // GetStatus returns the current keepalived status.
-func GetStatus() *Status {
+func GetStatus(cfg *config.Config) *Status {
 	status := &Status{
 		ConfigPath: FindConfigPath(),
+		Priority:   cfg.GetPriority(),
 	}
 
 	status.Running = IsRunning()
@@ -98,59 +173,96 @@ func GetStatus() *Status {
 		}
 	}
 
-	// Try to get VRRP state
+	for _, inst := range cfg.GetInstances() {
+		is := InstanceStatus{
+			Name:      inst.Name,
+			Interface: inst.Iface,
+			VRID:      inst.VRID,
+			VIPs:      inst.VIPAddrs(),
+			Priority:  cfg.GetInstancePriority(inst),
+			Group:     cfg.GetSyncGroup(inst.Name),
+			State:     instanceState(inst, status.Running),
+		}
+		status.Instances = append(status.Instances, is)
+		if inst.Name == "GATEWAY" {
+			status.VRRPState = is.State
+		}
+	}
+
+	for _, group := range cfg.SyncGroups {
+		status.Groups = append(status.Groups, GroupStatus{
+			Name:      group.Name,
+			Instances: group.Instances,
+			State:     groupState(group, status.Instances),
+		})
+	}
+
+	return status
+}
+
+// groupState determines the state of a sync group from the group notify
+// state file, falling back to the member states.
+func groupState(group config.SyncGroupConfig, instances []InstanceStatus) string {
+	members := make(map[string]bool)
+	for _, name := range group.Instances {
+		members[name] = true
+	}
+
+	// Members that disagree mean the group split across routers,
+	// whatever the last group notification said
+	memberState := ""
+	for _, inst := range instances {
+		if !members[inst.Name] || inst.State == "" {
+			continue
+		}
+		if memberState != "" && memberState != inst.State {
+			return "SPLIT"
+		}
+		memberState = inst.State
+	}
+
+	if data, err := os.ReadFile(StateFile(group.Name)); err == nil {
+		state := strings.TrimSpace(string(data))
+		if state != "" && state != "UNKNOWN" {
+			return state
+		}
+	}
+	return memberState
+}
+
+// instanceState determines the VRRP state of an instance.
+func instanceState(inst config.InstanceConfig, running bool) string {
 	// 1. Check state file (updated by notify scripts)
 	// Only trust if not UNKNOWN (as we initialize it to UNKNOWN)
-	result := exec.RunWithTimeout("cat", 2*time.Second, "/tmp/keepalived.GATEWAY.state")
-	if result.Success() {
-		state := strings.TrimSpace(result.Stdout)
+	if data, err := os.ReadFile(StateFile(inst.Name)); err == nil {
+		state := strings.TrimSpace(string(data))
 		if state != "" && state != "UNKNOWN" {
-			status.VRRPState = state
+			return state
 		}
 	}
 
-	// 2. Fallback: Check if VIP is actually assigned to the interface
+	// 2. Fallback: Check if the VIPs are actually assigned to the interface
 	// This provides a reliable source of truth even if notify scripts fail
-	if status.VRRPState == "" || status.VRRPState == "UNKNOWN" {
-		cfgFile := FindConfigPath()
-		if data, err := os.ReadFile(cfgFile); err == nil {
-			content := string(data)
-			// Extract VIP and Interface from config
-			vip := ""
-			iface := ""
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "virtual_ipaddress {") {
-					// Next line usually contains VIP
-					continue
-				}
-				if strings.Contains(line, "/32 dev") {
-					parts := strings.Fields(line)
-					if len(parts) >= 3 {
-						vip = strings.Split(parts[0], "/")[0]
-						iface = parts[2]
-					}
-				}
-			}
-
-			if vip != "" && iface != "" {
-				// Check if IP exists on interface
-				// On OpenWrt/BusyBox, grep might behave differently, so we check for exact match or subnet
-				checkCmd := fmt.Sprintf("ip addr show dev %s | grep -F '%s/'", iface, vip)
-				res := exec.RunWithTimeout("sh", 2*time.Second, "-c", checkCmd)
-				if res.Success() {
-					status.VRRPState = "MASTER"
-				} else if status.Running {
-					// If running but no VIP, we are definitely BACKUP (or FAULT)
-					// But only if we are sure service is running
-					status.VRRPState = "BACKUP"
-				}
-			}
-		}
+	if len(inst.VIPs) == 0 || inst.Iface == "" {
+		return ""
 	}
-
-	return status
+	result := exec.RunWithTimeout("ip", 2*time.Second, "addr", "show", "dev", inst.Iface)
+	if !result.Success() {
+		return ""
+	}
+	ip, _, err := config.ParseVIP(inst.VIPs[0])
+	if err != nil {
+		return ""
+	}
+	if strings.Contains(result.Stdout, " "+ip.String()+"/") {
+		return "MASTER"
+	}
+	if running {
+		// If running but no VIP, we are definitely BACKUP (or FAULT)
+		// But only if we are sure service is running
+		return "BACKUP"
+	}
+	return ""
 }
 
 // IsRunning checks if keepalived is running.
@@ -16,21 +16,31 @@ import (
 
 // TemplateData holds data for keepalived config template.
 type TemplateData struct {
-	Role            string
+	Role          string
+	Instances     []InstanceData
+	SyncGroups    []config.SyncGroupConfig
+	AdvertInt     int
+	Preempt       bool
+	PreemptDelay  int
+	HealthMode    string
+	CheckInterval int
+	CheckScript   string
+	TrackMode     string
+	TrackFile     string
+	TrackWeight   int
+	AgentBinary   string
+}
+
+// InstanceData holds data for a single vrrp_instance block.
+type InstanceData struct {
+	Name            string
 	Interface       string
 	VirtualRouterID int
 	Priority        int
-	AdvertInt       int
-	VIP             string
+	VIPs            []string // address/prefix
 	PeerIP          string
 	SelfIP          string
-	Preempt         bool
-	PreemptDelay    int
-	HealthMode      string
-	CheckInterval   int
-	CheckScript     string
-	TrackWeight     int
-	AgentBinary     string
+	IPv6            bool // Rendered as a VRRPv3 instance
 }
 
 const keepalivedTemplate = `# Gateway Agent Keepalived Configuration
@@ -42,48 +52,83 @@ global_defs {
     enable_script_security
 }
 
+{{ if eq .TrackMode "file" -}}
+# Written by "gateway-agent run" on every health transition with the
+# priority to give up: 0 = healthy, {{ neg .TrackWeight }} = unhealthy, in between = degraded
+vrrp_track_file chk_gateway {
+    file "{{ .TrackFile }}"
+    weight -1
+}
+{{- else -}}
+# fall/rise are left at 1: "check" reports the agent's debounced state
 vrrp_script chk_gateway {
-    script "{{ .AgentBinary }} check --mode={{ .HealthMode }}"
+    script "{{ .CheckScript }}"
     interval {{ .CheckInterval }}
     weight {{ .TrackWeight }}
-    fall 3
-    rise 2
     user root
     init_fail
 }
+{{- end }}
+
+{{- range .SyncGroups }}
+
+vrrp_sync_group {{ .Name }} {
+    group {
+    {{- range .Instances }}
+        {{ . }}
+    {{- end }}
+    }
+    # Members all track chk_gateway with the same weight
+    sync_group_tracking_weight
+
+    notify_master "{{ $.AgentBinary }} notify --group MASTER {{ .Name }}"
+    notify_backup "{{ $.AgentBinary }} notify --group BACKUP {{ .Name }}"
+    notify_fault  "{{ $.AgentBinary }} notify --group FAULT {{ .Name }}"
+}
+{{- end }}
+{{- range .Instances }}
 
-vrrp_instance GATEWAY {
+vrrp_instance {{ .Name }} {
     state BACKUP
     interface {{ .Interface }}
+    {{- if .IPv6 }}
+    version 3
+    {{- end }}
     virtual_router_id {{ .VirtualRouterID }}
     priority {{ .Priority }}
-    advert_int {{ .AdvertInt }}
-    {{ if .Preempt }}preempt_delay {{ .PreemptDelay }}{{ else }}nopreempt{{ end }}
+    advert_int {{ $.AdvertInt }}
+    {{ if $.Preempt }}preempt_delay {{ $.PreemptDelay }}{{ else }}nopreempt{{ end }}
+    {{- if not .IPv6 }}
 
     authentication {
         auth_type PASS
         auth_pass gateway
     }
+    {{- end }}
+    {{- if .PeerIP }}
 
-    {{ if .PeerIP }}
-    unicast_src_ip {{ .SelfIP }}
-    unicast_peer {
+    {{ if .SelfIP }}unicast_src_ip {{ .SelfIP }}
+    {{ end }}unicast_peer {
         {{ .PeerIP }}
     }
-    {{ end }}
+    {{- end }}
 
     virtual_ipaddress {
-        {{ .VIP }}/32 dev {{ .Interface }}
+    {{- $iface := .Interface }}
+    {{- range .VIPs }}
+        {{ . }} dev {{ $iface }}
+    {{- end }}
     }
 
-    track_script {
+    {{ if eq $.TrackMode "file" }}track_file{{ else }}track_script{{ end }} {
         chk_gateway
     }
 
-    notify_master "{{ .AgentBinary }} notify MASTER"
-    notify_backup "{{ .AgentBinary }} notify BACKUP"
-    notify_fault  "{{ .AgentBinary }} notify FAULT"
+    notify_master "{{ $.AgentBinary }} notify MASTER {{ .Name }}"
+    notify_backup "{{ $.AgentBinary }} notify BACKUP {{ .Name }}"
+    notify_fault  "{{ $.AgentBinary }} notify FAULT {{ .Name }}"
 }
+{{- end }}
 `
 
 // Renderer handles keepalived configuration rendering.
@@ -104,6 +149,9 @@ func (r *Renderer) Render() (string, error) {
 		"now": func() string {
 			return time.Now().Format(time.RFC3339)
 		},
+		"neg": func(i int) int {
+			return -i
+		},
 	}
 
 	tmpl, err := template.New("keepalived").Funcs(funcMap).Parse(keepalivedTemplate)
@@ -120,12 +168,9 @@ func (r *Renderer) Render() (string, error) {
 }
 
 func (r *Renderer) buildTemplateData() *TemplateData {
-	// Determine track weight based on role
-	// Secondary uses negative weight to drop priority when unhealthy
-	trackWeight := 0
-	if r.cfg.Role == config.RoleSecondary {
-		trackWeight = -200 // Enough to drop below primary's priority
-	}
+	// Both roles drop by the same weight when unhealthy; see
+	// config.TrackWeight for how this preserves the tie-break.
+	trackWeight := r.cfg.TrackWeight()
 
 	// Find agent binary path
 	agentBinary := FindAgentBinary()
@@ -139,22 +184,34 @@ func (r *Renderer) buildTemplateData() *TemplateData {
 		}
 	}
 
+	var instances []InstanceData
+	for _, inst := range r.cfg.GetInstances() {
+		instances = append(instances, InstanceData{
+			Name:            inst.Name,
+			Interface:       inst.Iface,
+			VirtualRouterID: inst.VRID,
+			Priority:        r.cfg.GetInstancePriority(inst),
+			VIPs:            inst.VIPAddrs(),
+			PeerIP:          inst.PeerIP,
+			SelfIP:          inst.SelfIP,
+			IPv6:            inst.IsIPv6(),
+		})
+	}
+
 	return &TemplateData{
-		Role:            string(r.cfg.Role),
-		Interface:       r.cfg.LAN.Iface,
-		VirtualRouterID: r.cfg.Keepalived.VRID,
-		Priority:        r.cfg.GetPriority(),
-		AdvertInt:       r.cfg.Keepalived.AdvertInt,
-		VIP:             r.cfg.LAN.VIP,
-		PeerIP:          r.cfg.Routers.PeerIP,
-		SelfIP:          r.cfg.Routers.SelfIP,
-		Preempt:         r.cfg.Failover.Preempt,
-		PreemptDelay:    r.cfg.Failover.PreemptDelaySec,
-		HealthMode:      string(r.cfg.Health.Mode),
-		CheckInterval:   r.cfg.Health.IntervalSec,
-		CheckScript:     fmt.Sprintf("%s check --mode=%s", agentBinary, r.cfg.Health.Mode),
-		TrackWeight:     trackWeight,
-		AgentBinary:     agentBinary,
+		Role:          string(r.cfg.Role),
+		Instances:     instances,
+		SyncGroups:    r.cfg.SyncGroups,
+		AdvertInt:     r.cfg.Keepalived.AdvertInt,
+		Preempt:       r.cfg.Failover.Preempt,
+		PreemptDelay:  r.cfg.Failover.PreemptDelaySec,
+		HealthMode:    string(r.cfg.Health.Mode),
+		CheckInterval: r.cfg.Health.IntervalSec,
+		CheckScript:   fmt.Sprintf("%s check --mode=%s", agentBinary, r.cfg.Health.Mode),
+		TrackMode:     string(r.cfg.Keepalived.Track),
+		TrackFile:     r.cfg.Keepalived.TrackFile,
+		TrackWeight:   trackWeight,
+		AgentBinary:   agentBinary,
 	}
 }
 
@@ -57,6 +57,8 @@ func (e *RealExecutor) Run(ctx context.Context, name string, args ...string) *Re
 	}
 
 	cmd := exec.CommandContext(ctx, name, args...)
+	// Don't wait on grandchildren holding the output pipes after a timeout
+	cmd.WaitDelay = time.Second
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -0,0 +1,57 @@
+//go:build linux
+
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// sendUnsolicitedNA sends an unsolicited Neighbor Advertisement for vip to
+// the all-nodes multicast group using a raw ICMPv6 socket. The kernel fills
+// in the ICMPv6 checksum for raw sockets.
+func sendUnsolicitedNA(vip, iface string) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return fmt.Errorf("interface %q not found: %w", iface, err)
+	}
+	target := net.ParseIP(vip).To16()
+
+	conn, err := net.ListenIP("ip6:ipv6-icmp", &net.IPAddr{IP: target, Zone: iface})
+	if err != nil {
+		return fmt.Errorf("open ICMPv6 socket: %w", err)
+	}
+	defer conn.Close()
+
+	// NDP messages must be sent with hop limit 255 (RFC 4861 7.1.2)
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, 255)
+	})
+	if sockErr != nil {
+		return fmt.Errorf("set hop limit: %w", sockErr)
+	}
+
+	// Type 136 (NA), flags Router|Override, target, target link-layer option
+	msg := make([]byte, 0, 32)
+	msg = append(msg, 136, 0, 0, 0)
+	msg = append(msg, 0xa0, 0, 0, 0)
+	msg = append(msg, target...)
+	if len(ifi.HardwareAddr) == 6 {
+		msg = append(msg, 2, 1)
+		msg = append(msg, ifi.HardwareAddr...)
+	}
+
+	dst := &net.IPAddr{IP: net.ParseIP("ff02::1"), Zone: iface}
+	for i := 0; i < 3; i++ {
+		if _, err := conn.WriteTo(msg, dst); err != nil {
+			return fmt.Errorf("send NA: %w", err)
+		}
+	}
+	return nil
+}
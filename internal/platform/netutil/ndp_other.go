@@ -0,0 +1,9 @@
+//go:build !linux
+
+package netutil
+
+import "fmt"
+
+func sendUnsolicitedNA(vip, iface string) error {
+	return fmt.Errorf("unsolicited NA is only supported on linux")
+}
@@ -17,6 +17,8 @@ type InterfaceInfo struct {
 	IPv4    string
 	CIDR    string
 	Netmask string
+	IPv6    string // First global unicast IPv6 address, if any
+	CIDR6   string
 	MAC     string
 	Up      bool
 }
@@ -89,11 +91,15 @@ func GetInterfaceInfo(name string) (*InterfaceInfo, error) {
 	for _, addr := range addrs {
 		if ipNet, ok := addr.(*net.IPNet); ok {
 			if ipv4 := ipNet.IP.To4(); ipv4 != nil {
-				info.IPv4 = ipv4.String()
-				info.CIDR = ipNet.String()
-				info.Netmask = fmt.Sprintf("%d.%d.%d.%d",
-					ipNet.Mask[0], ipNet.Mask[1], ipNet.Mask[2], ipNet.Mask[3])
-				break
+				if info.IPv4 == "" {
+					info.IPv4 = ipv4.String()
+					info.CIDR = ipNet.String()
+					info.Netmask = fmt.Sprintf("%d.%d.%d.%d",
+						ipNet.Mask[0], ipNet.Mask[1], ipNet.Mask[2], ipNet.Mask[3])
+				}
+			} else if info.IPv6 == "" && ipNet.IP.IsGlobalUnicast() {
+				info.IPv6 = ipNet.IP.String()
+				info.CIDR6 = ipNet.String()
 			}
 		}
 	}
@@ -248,11 +254,54 @@ func GetDefaultGateway() (string, error) {
 	return "", fmt.Errorf("could not determine default gateway")
 }
 
+// IsIPv6 reports whether addr is a valid IPv6 (not IPv4) address.
+func IsIPv6(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
+
+// HostPrefix returns the single-host prefix for addr ("/32" or "/128").
+func HostPrefix(addr string) string {
+	if IsIPv6(addr) {
+		return "/128"
+	}
+	return "/32"
+}
+
+// SendUnsolicitedNA announces an IPv6 VIP with unsolicited Neighbor
+// Advertisements, the IPv6 counterpart of a GARP.
+func SendUnsolicitedNA(vip, iface string) error {
+	if !IsIPv6(vip) {
+		return fmt.Errorf("invalid IPv6 address: %s", vip)
+	}
+
+	// ndsend (from vzctl/ndisc tools) is the simplest if present
+	if exec.CommandExists("ndsend") {
+		result := exec.RunWithTimeout("ndsend", 5*time.Second, vip, iface)
+		if result.Success() {
+			return nil
+		}
+	}
+
+	err := sendUnsolicitedNA(vip, iface)
+	if err == nil {
+		return nil
+	}
+
+	// Flushing our own neighbor cache doesn't update the peers' caches, so
+	// it is only a best effort and the announcement still failed
+	if exec.CommandExists("ip") {
+		exec.RunWithTimeout("ip", 3*time.Second, "-6", "neigh", "flush", "dev", iface)
+	}
+
+	return fmt.Errorf("send unsolicited NA: %w", err)
+}
+
 // AddVIP adds a VIP to an interface.
 func AddVIP(vip, iface string) error {
 	if exec.CommandExists("ip") {
 		result := exec.RunWithTimeout("ip", 5*time.Second,
-			"addr", "add", vip+"/32", "dev", iface)
+			"addr", "add", vip+HostPrefix(vip), "dev", iface)
 		if result.Success() || strings.Contains(result.Stderr, "exists") {
 			return nil
 		}
@@ -265,7 +314,7 @@ func AddVIP(vip, iface string) error {
 func RemoveVIP(vip, iface string) error {
 	if exec.CommandExists("ip") {
 		result := exec.RunWithTimeout("ip", 5*time.Second,
-			"addr", "del", vip+"/32", "dev", iface)
+			"addr", "del", vip+HostPrefix(vip), "dev", iface)
 		if result.Success() || strings.Contains(result.Stderr, "not exist") {
 			return nil
 		}
@@ -322,6 +371,19 @@ func HasVIP(vip, iface string) (bool, error) {
 		return true, nil
 	}
 
+	// Check every address: an IPv6 VIP is usually not the first global one
+	if ip := net.ParseIP(vip); ip != nil {
+		if ifi, err := net.InterfaceByName(iface); err == nil {
+			if addrs, err := ifi.Addrs(); err == nil {
+				for _, addr := range addrs {
+					if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+						return true, nil
+					}
+				}
+			}
+		}
+	}
+
 	// Check secondary IPs using ip command
 	if exec.CommandExists("ip") {
 		result := exec.RunWithTimeout("ip", 5*time.Second,